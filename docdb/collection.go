@@ -0,0 +1,164 @@
+package docdb
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var collectionName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type Collection struct {
+	*DocDB
+	name string
+}
+
+func (c Collection) Name() string {
+	return c.name
+}
+
+type Collections struct {
+	mu               sync.RWMutex
+	dir              string
+	snapshotInterval time.Duration
+	opts             []Option
+	collections      map[string]*Collection
+}
+
+func (cs *Collections) Create(name string) (*Collection, error) {
+	if !collectionName.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if _, ok := cs.collections[name]; ok {
+		return nil, ErrAlreadyExists
+	}
+	c, err := cs.open(name)
+	if err != nil {
+		log.Printf("failed to create collection %s: %s", name, err)
+		return nil, ErrFatal
+	}
+	cs.collections[name] = c
+	return c, nil
+}
+
+func (cs *Collections) Get(name string) (*Collection, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	c, ok := cs.collections[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+func (cs *Collections) List() []string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	names := make([]string, 0, len(cs.collections))
+	for name := range cs.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (cs *Collections) Drop(name string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	c, ok := cs.collections[name]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(cs.collections, name)
+
+	if err := c.Close(); err != nil {
+		log.Printf("failed to close collection %s: %s", name, err)
+	}
+	if cs.dir != "" {
+		if err := os.RemoveAll(filepath.Join(cs.dir, name)); err != nil {
+			log.Printf("failed to remove collection %s: %s", name, err)
+			return ErrFatal
+		}
+	}
+	return nil
+}
+
+func (cs *Collections) Close() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var err error
+	for name, c := range cs.collections {
+		if cerr := c.Close(); cerr != nil {
+			log.Printf("failed to close collection %s: %s", name, cerr)
+			err = cerr
+		}
+	}
+	cs.collections = map[string]*Collection{}
+	return err
+}
+
+func (cs *Collections) open(name string) (*Collection, error) {
+	if cs.dir == "" {
+		d, err := Open(cs.opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &Collection{DocDB: d, name: name}, nil
+	}
+
+	s, err := NewFileStore(filepath.Join(cs.dir, name), cs.snapshotInterval)
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]Option{WithStore(s)}, cs.opts...)
+	d, err := Open(opts...)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &Collection{DocDB: d, name: name}, nil
+}
+
+func NewCollections(dir string, snapshotInterval time.Duration, opts ...Option) (*Collections, error) {
+	cs := &Collections{
+		dir:              dir,
+		snapshotInterval: snapshotInterval,
+		opts:             opts,
+		collections:      map[string]*Collection{},
+	}
+	if dir == "" {
+		return cs, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !collectionName.MatchString(e.Name()) {
+			continue
+		}
+		c, err := cs.open(e.Name())
+		if err != nil {
+			cs.Close()
+			return nil, err
+		}
+		cs.collections[e.Name()] = c
+	}
+	return cs, nil
+}
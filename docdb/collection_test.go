@@ -0,0 +1,97 @@
+package docdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  bool
+	}{
+		{
+			name: "In-memory collections",
+			dir:  false,
+		},
+		{
+			name: "Persistent collections",
+			dir:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := ""
+			if tt.dir {
+				dir = t.TempDir()
+			}
+			cs, err := NewCollections(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			books, err := cs.Create("books")
+			if err != nil {
+				t.Fatalf("Collections.Create() error = %v", err)
+			}
+			if _, err := cs.Create("users"); err != nil {
+				t.Fatalf("Collections.Create() error = %v", err)
+			}
+			if _, err := cs.Create("books"); !errors.Is(err, ErrAlreadyExists) {
+				t.Errorf("Collections.Create() error = %v, want %v", err, ErrAlreadyExists)
+			}
+			if _, err := cs.Create("../books"); !errors.Is(err, ErrInvalidName) {
+				t.Errorf("Collections.Create() error = %v, want %v", err, ErrInvalidName)
+			}
+
+			id, err := books.Add(map[string]any{"name": "x"})
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+			users, err := cs.Get("users")
+			if err != nil {
+				t.Fatalf("Collections.Get() error = %v", err)
+			}
+			if ids, _ := books.lookup(`name="x"`); !cmp.Equal([]string{id}, ids) {
+				t.Errorf("document is not indexed in its collection: %v", ids)
+			}
+			if ids, _ := users.lookup(`name="x"`); len(ids) != 0 {
+				t.Errorf("document is indexed in other collection: %v", ids)
+			}
+
+			if err := cs.Drop("users"); err != nil {
+				t.Fatalf("Collections.Drop() error = %v", err)
+			}
+			if err := cs.Drop("users"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Collections.Drop() error = %v, want %v", err, ErrNotFound)
+			}
+			if diff := cmp.Diff([]string{"books"}, cs.List()); diff != "" {
+				t.Errorf("Collections.List() mismatch (-want +got):\n%s", diff)
+			}
+
+			if !tt.dir {
+				return
+			}
+			if err := cs.Close(); err != nil {
+				t.Fatal(err)
+			}
+			cs, err = NewCollections(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+			if diff := cmp.Diff([]string{"books"}, cs.List()); diff != "" {
+				t.Errorf("Collections.List() after reopen mismatch (-want +got):\n%s", diff)
+			}
+			books, err = cs.Get("books")
+			if err != nil {
+				t.Fatalf("Collections.Get() error = %v", err)
+			}
+			if _, err := books.Get(id); err != nil {
+				t.Errorf("can not get document after reopen: %v", err)
+			}
+		})
+	}
+}
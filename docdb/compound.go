@@ -0,0 +1,137 @@
+package docdb
+
+import (
+	"log"
+	"strings"
+
+	"github.com/x-color/docdb-in-go/query"
+)
+
+// compoundPath is the path under which a compound index keeps the ordered
+// values of its last path, for the given values of the other paths.
+func compoundPath(def IndexDefinition, prefix []string) string {
+	return def.name() + "\x00\x00" + strings.Join(prefix, "\x00")
+}
+
+func (d DocDB) addCompoundKeys(keys map[indexKey]bool, doc map[string]any) {
+	compounds := d.indexes.compoundList()
+	if len(compounds) == 0 || doc == nil {
+		return
+	}
+
+	values := make(map[string][]any)
+	leaves(doc, "", func(path string, v any) {
+		values[path] = append(values[path], v)
+	})
+
+	for _, def := range compounds {
+		last := def.Paths[len(def.Paths)-1]
+		prefixes := [][]string{{}}
+		for _, path := range def.Paths[:len(def.Paths)-1] {
+			var next [][]string
+			for _, prefix := range prefixes {
+				for _, v := range values[path] {
+					p := append(append([]string{}, prefix...), typedKey(v))
+					next = append(next, p)
+				}
+			}
+			prefixes = next
+		}
+
+		for _, prefix := range prefixes {
+			path := compoundPath(def, prefix)
+			for _, v := range values[last] {
+				keys[indexKey{path: path, value: compoundValue(v)}] = true
+			}
+		}
+	}
+}
+
+// compoundValue is the ordered value of v. Values which have no order are kept
+// as strings, so that equalities on them still use the index.
+func compoundValue(v any) any {
+	if ovs := orderedValues(v); len(ovs) > 0 {
+		return ovs[0]
+	}
+	return typedKey(v)
+}
+
+// compoundCandidates looks up the compound index which covers the most
+// queries. It returns the postings and the queries they cover.
+func (d DocDB) compoundCandidates(qs query.Queries) (Postings, map[int]bool, error) {
+	var best IndexDefinition
+	var bestPrefix []string
+	var bestCovered map[int]bool
+	bestLast := -1
+	for _, def := range d.indexes.compoundList() {
+		if !def.Ready {
+			continue
+		}
+		prefix, covered, last, ok := coverCompound(def, qs)
+		if ok && len(covered) > len(bestCovered) {
+			best, bestPrefix, bestCovered, bestLast = def, prefix, covered, last
+		}
+	}
+	if bestLast < 0 {
+		return Postings{}, nil, nil
+	}
+
+	q := qs[bestLast]
+	path := compoundPath(best, bestPrefix)
+
+	var p Postings
+	var err error
+	if q.Op == query.OpeEq {
+		b := &Bound{Value: compoundValue(q.Literal()), Inclusive: true}
+		p, err = d.index.Range(path, b, b)
+	} else {
+		lo, hi, _ := q.Bounds()
+		p, err = d.rangeLookup(path, lo, hi)
+	}
+	if err != nil {
+		log.Printf("failed to get data from index: %v: %s", q, err)
+		return Postings{}, nil, ErrFatal
+	}
+	return p, bestCovered, nil
+}
+
+// coverCompound finds queries for the compound index: equalities on all but
+// the last path, and an equality or a range on the last path.
+func coverCompound(def IndexDefinition, qs query.Queries) ([]string, map[int]bool, int, bool) {
+	covered := make(map[int]bool)
+	var prefix []string
+	for _, path := range def.Paths[:len(def.Paths)-1] {
+		found := false
+		for i, q := range qs {
+			if covered[i] || q.Coerce || q.Op != query.OpeEq || strings.Join(q.Keys, ".") != path {
+				continue
+			}
+			prefix = append(prefix, typedKey(q.Literal()))
+			covered[i] = true
+			found = true
+			break
+		}
+		if !found {
+			return nil, nil, -1, false
+		}
+	}
+
+	last := -1
+	for i, q := range qs {
+		if covered[i] || q.Coerce || strings.Join(q.Keys, ".") != def.Paths[len(def.Paths)-1] {
+			continue
+		}
+		if q.Op == query.OpeEq {
+			last = i
+			break
+		}
+		if _, _, ok := q.Bounds(); last < 0 && ok {
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil, nil, -1, false
+	}
+	covered[last] = true
+	return prefix, covered, last, true
+}
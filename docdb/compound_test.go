@@ -0,0 +1,160 @@
+package docdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/x-color/docdb-in-go/query"
+)
+
+func TestDocDB_CreateCompoundIndex(t *testing.T) {
+	docs := map[string]map[string]any{
+		"a": {"category": "book", "detail": map[string]any{"price": 10}},
+		"b": {"category": "book", "detail": map[string]any{"price": 30}},
+		"c": {"category": "music", "detail": map[string]any{"price": 10}},
+		"d": {"category": []any{"book", "music"}, "detail": map[string]any{"price": 15}},
+		"e": {"category": "book", "stock": true},
+	}
+	tests := []struct {
+		name           string
+		paths          []string
+		q              string
+		wantCovered    []int
+		wantCandidates []string
+		wantErr        error
+	}{
+		{
+			name:           "Equality and range",
+			paths:          []string{"category", "detail.price"},
+			q:              `category:"book" detail.price:<20`,
+			wantCovered:    []int{0, 1},
+			wantCandidates: []string{"a", "d"},
+		},
+		{
+			name:           "Equality and inclusive range",
+			paths:          []string{"category", "detail.price"},
+			q:              `category:"book" detail.price:[15 TO 30]`,
+			wantCovered:    []int{0, 1},
+			wantCandidates: []string{"b", "d"},
+		},
+		{
+			name:           "Equalities",
+			paths:          []string{"category", "detail.price"},
+			q:              `detail.price:10 category:"music"`,
+			wantCovered:    []int{0, 1},
+			wantCandidates: []string{"c"},
+		},
+		{
+			name:           "Unorderable value",
+			paths:          []string{"category", "stock"},
+			q:              `category:"book" stock:true`,
+			wantCovered:    []int{0, 1},
+			wantCandidates: []string{"e"},
+		},
+		{
+			name:           "Range on prefix is not covered",
+			paths:          []string{"category", "detail.price"},
+			q:              `category:>a detail.price:<20`,
+			wantCovered:    nil,
+			wantCandidates: []string{"a", "c", "d"},
+		},
+		{
+			name:           "Missing prefix is not covered",
+			paths:          []string{"category", "detail.price"},
+			q:              `detail.price:<20`,
+			wantCovered:    nil,
+			wantCandidates: []string{"a", "c", "d"},
+		},
+		{
+			name:    "Single path",
+			paths:   []string{"category"},
+			wantErr: ErrInvalidIndex,
+		},
+		{
+			name:    "Empty path",
+			paths:   []string{"category", ""},
+			wantErr: ErrInvalidIndex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(WithIndexes(IndexDefinition{Path: "detail.price", Kind: IndexRange}))
+			defer d.Close()
+			for id, doc := range docs {
+				if _, _, err := d.Upsert(id, doc); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := d.CreateCompoundIndex(tt.paths...); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DocDB.CreateCompoundIndex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			waitIndexes(t, d)
+
+			qs, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, covered, err := d.compoundCandidates(qs.Queries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotCovered []int
+			for i := range covered {
+				gotCovered = append(gotCovered, i)
+			}
+			sortInts := cmpopts.SortSlices(func(a, b int) bool { return a < b })
+			if diff := cmp.Diff(tt.wantCovered, gotCovered, sortInts); diff != "" {
+				t.Errorf("DocDB.compoundCandidates() covered mismatch (-want +got):\n%s", diff)
+			}
+
+			candidates, err := d.candidates(qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sortStrings := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+			if diff := cmp.Diff(tt.wantCandidates, candidates, sortStrings); diff != "" {
+				t.Errorf("DocDB.candidates() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_compoundReindex(t *testing.T) {
+	d := NewDocDB(WithIndexes(IndexDefinition{Paths: []string{"category", "price"}, Kind: IndexCompound}))
+	defer d.Close()
+	if _, _, err := d.Upsert("a", map[string]any{"category": "book", "price": 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Update("a", map[string]any{"category": "music", "price": 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{q: `category:"book" price:10`, want: []string{}},
+		{q: `category:"music" price:10`, want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			qs, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.candidates(qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("DocDB.candidates() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package docdb
+
+type Condition func(rev uint64, exists bool) bool
+
+func IfMatch(revs ...uint64) Condition {
+	return func(rev uint64, exists bool) bool {
+		if !exists {
+			return false
+		}
+		if len(revs) == 0 {
+			return true
+		}
+		return containsRev(revs, rev)
+	}
+}
+
+func IfNoneMatch(revs ...uint64) Condition {
+	return func(rev uint64, exists bool) bool {
+		if !exists {
+			return true
+		}
+		if len(revs) == 0 {
+			return false
+		}
+		return !containsRev(revs, rev)
+	}
+}
+
+func checkConditions(conds []Condition, rev uint64, exists bool) error {
+	for _, cond := range conds {
+		if !cond(rev, exists) {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+func containsRev(revs []uint64, rev uint64) bool {
+	for _, r := range revs {
+		if r == rev {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,16 @@
 package docdb
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/patrickmn/go-cache"
 	"github.com/x-color/docdb-in-go/query"
 )
 
@@ -17,174 +18,1036 @@ var (
 	ErrUnknown  = errors.New("unknown error")
 	ErrFatal    = errors.New("fatal error")
 	ErrNotFound = errors.New("not found error")
+
+	ErrAlreadyExists = errors.New("already exists error")
+	ErrInvalidName   = errors.New("invalid name error")
+	ErrInvalidID     = errors.New("invalid id error")
+	ErrConflict      = errors.New("conflict error")
+	ErrInvalidExpiry = errors.New("invalid expiry error")
+
+	ErrInvalidPatch = errors.New("invalid patch error")
+	ErrTestFailed   = errors.New("patch test failed error")
+
+	ErrInvalidIndex = errors.New("invalid index error")
 )
 
 type DocDB struct {
-	db      *cache.Cache
-	indexDb *cache.Cache
+	mu        *sync.RWMutex
+	rev       *uint64
+	store     Store
+	index     Index
+	indexes   *indexDefs
+	uniques   *uniqueIndex
+	texts     *textIndex
+	idField   string
+	retention retention
+	expiry    *expiryQueue
+	now       func() time.Time
+	done      chan struct{}
+	closeOnce *sync.Once
+}
+
+type options struct {
+	store          Store
+	index          Index
+	indexes        []IndexDefinition
+	idField        string
+	retention      retention
+	expiryInterval time.Duration
+}
+
+type Option func(*options)
+
+func WithStore(s Store) Option {
+	return func(o *options) {
+		o.store = s
+	}
+}
+
+func WithIndex(i Index) Option {
+	return func(o *options) {
+		o.index = i
+	}
+}
+
+func WithIDField(field string) Option {
+	return func(o *options) {
+		o.idField = field
+	}
+}
+
+func WithHistoryRetention(versions int, age time.Duration) Option {
+	return func(o *options) {
+		o.retention = retention{versions: versions, age: age}
+	}
+}
+
+func WithExpiryInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.expiryInterval = interval
+	}
 }
 
 func (d DocDB) Add(doc map[string]any) (string, error) {
-	id := uuid.New().String()
-	b, err := json.Marshal(doc)
+	id, _, err := d.AddWithRevision(doc)
+	return id, err
+}
+
+// AddWithRevision adds doc, and returns its id along with its revision.
+func (d DocDB) AddWithRevision(doc map[string]any) (string, uint64, error) {
+	id, err := d.docID(doc)
+	if err != nil {
+		return "", 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, _, err := d.loadCurrent(id); err == nil {
+		return "", 0, ErrAlreadyExists
+	} else if !errors.Is(err, ErrNotFound) {
+		return "", 0, err
+	}
+	rev, err := d.put(id, record{}, nil, doc)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return id, rev, nil
+}
+
+func (d DocDB) Upsert(id string, doc map[string]any, conds ...Condition) (uint64, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, old, err := d.loadCurrent(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, false, err
+	}
+	if err := checkConditions(conds, prev.Rev, old != nil); err != nil {
+		return 0, false, err
+	}
+	rev, err := d.put(id, prev, old, doc)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return rev, old == nil, nil
+}
+
+func (d DocDB) Update(id string, doc map[string]any, conds ...Condition) (uint64, error) {
+	return d.modify(id, conds, func(map[string]any) (map[string]any, error) {
+		return doc, nil
+	})
+}
+
+func (d DocDB) UpdateIfRevision(id string, doc map[string]any, rev uint64) (uint64, error) {
+	return d.Update(id, doc, IfMatch(rev))
+}
+
+func (d DocDB) MergePatch(id string, patch map[string]any, conds ...Condition) (uint64, error) {
+	return d.modify(id, conds, func(old map[string]any) (map[string]any, error) {
+		return mergePatch(old, patch), nil
+	})
+}
+
+func (d DocDB) JSONPatch(id string, ops []PatchOperation, conds ...Condition) (uint64, error) {
+	return d.modify(id, conds, func(old map[string]any) (map[string]any, error) {
+		return jsonPatch(old, ops)
+	})
+}
+
+func (d DocDB) modify(id string, conds []Condition, fn func(old map[string]any) (map[string]any, error)) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, old, err := d.loadCurrent(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, err
+	}
+	if err := checkConditions(conds, prev.Rev, old != nil); err != nil {
+		return 0, err
+	}
+	if old == nil {
+		return 0, ErrNotFound
+	}
+	doc, err := fn(withExpiry(old, prev.ExpiresAt))
+	if err != nil {
+		return 0, err
+	}
+
+	return d.put(id, prev, old, doc)
+}
+
+func (d DocDB) put(id string, prev record, old, doc map[string]any) (uint64, error) {
+	c, err := d.prepare(id, prev, old, doc, *d.rev+1)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.checkUnique(id, c.doc); err != nil {
+		return 0, err
+	}
+
+	if err := d.store.Put(id, c.data); err != nil {
+		log.Printf("failed to store document: %s\n", err)
+		return 0, ErrFatal
+	}
+	*d.rev = c.rev
+	d.uniques.set(id, old, c.doc)
+	d.apply(c)
+
+	return c.rev, nil
+}
+
+// change is a write of a document which is ready to be stored. doc is nil
+// when the document is deleted, and rev is then the revision it had.
+type change struct {
+	id        string
+	rev       uint64
+	old       map[string]any
+	doc       map[string]any
+	expiresAt *time.Time
+	data      []byte
+}
+
+// prepare builds the record of doc as its revision rev.
+func (d DocDB) prepare(id string, prev record, old, doc map[string]any, rev uint64) (change, error) {
+	if err := d.checkID(id, doc); err != nil {
+		return change{}, err
+	}
+	expiresAt, doc, err := extractExpiry(doc)
+	if err != nil {
+		return change{}, err
+	}
+
+	r := record{
+		Rev:       rev,
+		UpdatedAt: d.now(),
+		ExpiresAt: expiresAt,
+	}
+	if old != nil {
+		r.History = d.retention.apply(append(prev.History, prev.version()), r.UpdatedAt)
+	}
+	b, err := r.encode(doc)
 	if err != nil {
 		log.Printf("failed to convert document to byte data: %s\n", err)
-		return "", ErrFatal
+		return change{}, ErrFatal
 	}
 
-	d.db.Set(id, b, 0)
-	d.index(id, doc)
+	return change{id: id, rev: rev, old: old, doc: doc, expiresAt: expiresAt, data: b}, nil
+}
 
-	return id, nil
+// apply brings the indexes in line with a stored change. Unique values are
+// set by the caller, as they are checked before the change is stored.
+func (d DocDB) apply(c change) {
+	d.texts.set(c.id, d.textTokens(c.doc))
+	d.reindexDoc(c.id, c.old, c.doc)
+	d.expiry.set(c.id, c.expiresAt)
+}
+
+// checkID rejects doc when its ID field names another document than id, so
+// that the field keeps identifying documents. A document without the field
+// is stored under id.
+func (d DocDB) checkID(id string, doc map[string]any) error {
+	if d.idField == "" || doc[d.idField] == nil {
+		return nil
+	}
+	docID, err := d.docID(doc)
+	if err != nil {
+		return err
+	}
+	if docID != id {
+		log.Printf("id in %s field differs from %s: %s", d.idField, id, docID)
+		return ErrInvalidID
+	}
+	return nil
+}
+
+func (d DocDB) docID(doc map[string]any) (string, error) {
+	if d.idField == "" {
+		return uuid.New().String(), nil
+	}
+
+	switch v := doc[d.idField].(type) {
+	case nil:
+		return uuid.New().String(), nil
+	case string:
+		if v != "" {
+			return v, nil
+		}
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	}
+	log.Printf("invalid id in %s field: %v", d.idField, doc[d.idField])
+	return "", ErrInvalidID
+}
+
+func (d DocDB) Delete(id string, conds ...Condition) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, old, err := d.loadCurrent(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := checkConditions(conds, prev.Rev, old != nil); err != nil {
+		return err
+	}
+	if old == nil {
+		return ErrNotFound
+	}
+
+	return d.remove(id, prev.Rev, old)
+}
+
+// remove deletes the document of the revision rev.
+func (d DocDB) remove(id string, rev uint64, old map[string]any) error {
+	if err := d.store.Delete(id, rev); err != nil {
+		log.Printf("failed to delete document: %s\n", err)
+		return ErrFatal
+	}
+	d.uniques.set(id, old, nil)
+	d.apply(change{id: id, old: old})
+
+	return nil
 }
 
 func (d DocDB) Get(id string) (map[string]any, error) {
-	item, ok := d.db.Get(id)
-	if !ok {
+	doc, _, err := d.GetWithRevision(id)
+	return doc, err
+}
+
+func (d DocDB) GetWithRevision(id string) (map[string]any, uint64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.get(id)
+}
+
+func (d DocDB) get(id string) (map[string]any, uint64, error) {
+	r, doc, err := d.load(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if r.expired(d.now()) {
+		return nil, 0, ErrNotFound
+	}
+	return withExpiry(doc, r.ExpiresAt), r.Rev, nil
+}
+
+// loadCurrent is load for writers. A document which has expired but has not
+// been reaped yet is removed, so that it is replaced as a new document.
+func (d DocDB) loadCurrent(id string) (record, map[string]any, error) {
+	r, doc, err := d.load(id)
+	if err != nil {
+		return record{}, nil, err
+	}
+	if r.expired(d.now()) {
+		if err := d.remove(id, r.Rev, doc); err != nil {
+			return record{}, nil, err
+		}
+		d.expiry.expired++
+		return record{}, nil, ErrNotFound
+	}
+	return r, doc, nil
+}
+
+func (d DocDB) load(id string) (record, map[string]any, error) {
+	b, err := d.store.Get(id)
+	if errors.Is(err, ErrNotFound) {
 		log.Printf("not found document by %s", id)
-		return nil, ErrNotFound
+		return record{}, nil, ErrNotFound
 	}
-	b, ok := item.([]byte)
-	if !ok {
-		log.Printf("unexpected data in %s", id)
-		return nil, ErrFatal
+	if err != nil {
+		log.Printf("failed to get document by %s: %s", id, err)
+		return record{}, nil, ErrFatal
 	}
-	doc := make(map[string]any)
-	if err := json.Unmarshal(b, &doc); err != nil {
+	r, doc, err := decodeRecord(b)
+	if err != nil {
 		log.Printf("failed to convert data to document: %s", err)
-		return nil, ErrFatal
+		return record{}, nil, ErrFatal
 	}
 
-	return doc, nil
+	return r, doc, nil
 }
 
-func (d DocDB) Search(qs query.Queries) ([]map[string]any, error) {
-	matchId := make(map[string]int)
-	for _, q := range qs {
-		if q.Op == query.OpeEq {
-			ids, err := d.lookup(fmt.Sprintf("%s=%s", strings.Join(q.Keys, "."), q.Value))
-			if err != nil {
-				log.Printf("failed to get data from index: %v", q)
-				return nil, ErrFatal
-			}
-			for _, id := range ids {
-				matchId[id]++
-			}
-		} else {
-			ids, err := d.lookup(strings.Join(q.Keys, "."))
-			if err != nil {
-				log.Printf("failed to get data from index: %v", q)
-				return nil, ErrFatal
-			}
-			for _, id := range ids {
-				matchId[id]++
-			}
-		}
-	}
+func (d DocDB) Search(e query.Expr) ([]map[string]any, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 
 	match := make([]map[string]any, 0)
-	for id, count := range matchId {
-		if count != len(qs) {
+	if e.Empty() {
+		return match, nil
+	}
+
+	ids, err := d.candidates(e)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		doc, _, err := d.get(id)
+		if errors.Is(err, ErrNotFound) {
 			continue
 		}
-		doc, err := d.Get(id)
 		if err != nil {
 			log.Printf("failed to get doc from main: %s", id)
 			return nil, ErrFatal
 		}
-		if qs.Match(doc) {
+		if e.Match(doc) {
 			match = append(match, map[string]any{
 				"id":       id,
 				"document": doc,
 			})
 		}
 	}
+
+	d.rank(e, match)
 	return match, nil
 }
 
-func (d DocDB) index(id string, doc map[string]any) {
-	pvs := getPathValues(doc, "")
-	d.setIndex(id, pvs)
-	ps := getPath(doc, "")
-	d.setIndex(id, ps)
+// rank scores hits of full-text queries by BM25, and sorts them by score.
+func (d DocDB) rank(e query.Expr, hits []map[string]any) {
+	texts := textQueries(e)
+	if len(texts) == 0 {
+		return
+	}
+
+	for _, hit := range hits {
+		var score float64
+		for _, q := range texts {
+			score += d.texts.score(strings.Join(q.Keys, "."), hit["id"].(string), query.Tokenize(q.Value))
+		}
+		hit["score"] = score
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i]["score"].(float64) > hits[j]["score"].(float64)
+	})
 }
 
-func (d DocDB) setIndex(id string, keys []string) {
-	for _, key := range keys {
-		v, ok := d.indexDb.Get(key)
+// textQueries returns the full-text queries of e, except negated ones.
+func textQueries(e query.Expr) query.Queries {
+	if e.Op == query.BoolNot {
+		return nil
+	}
+	var texts query.Queries
+	for _, q := range e.Queries {
+		if q.Op == query.OpeText {
+			texts = append(texts, q)
+		}
+	}
+	for _, x := range e.Exprs {
+		texts = append(texts, textQueries(x)...)
+	}
+	return texts
+}
+
+// candidates returns the IDs of documents which may match the expression.
+// Queries on unindexed paths are left to Expr.Match, and all documents are
+// scanned when the index cannot narrow them down.
+func (d DocDB) candidates(e query.Expr) ([]string, error) {
+	matched, ok, _, err := d.postings(e)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return d.scan()
+	}
+
+	ids := make([]string, 0, matched.Len())
+	matched.Each(func(n uint32) bool {
+		if id, ok := d.index.Resolve(n); ok {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	return ids, nil
+}
+
+// postings returns the documents which may match e. ok is false when the
+// index cannot narrow them down. exact is true when they are just the
+// documents which match e, so that they can be taken away for NOT.
+func (d DocDB) postings(e query.Expr) (Postings, bool, bool, error) {
+	switch e.Op {
+	case query.BoolOr:
+		return d.orPostings(e)
+	case query.BoolNot:
+		// NOT is applied as a difference from the other operands of AND,
+		// which are all documents when it stands alone.
+		return d.andPostings(query.Expr{Op: query.BoolAnd, Exprs: []query.Expr{e}})
+	default:
+		return d.andPostings(e)
+	}
+}
+
+func (d DocDB) andPostings(e query.Expr) (Postings, bool, bool, error) {
+	lists := make([]Postings, 0, len(e.Queries)+len(e.Exprs))
+	exact := true
+	p, covered, err := d.compoundCandidates(e.Queries)
+	if err != nil {
+		return Postings{}, false, false, err
+	}
+	if covered != nil {
+		lists = append(lists, p)
+		exact = false
+	}
+
+	for i := range e.Queries {
+		if covered[i] {
+			continue
+		}
+		p, ok, x, err := d.queryPostings(e.Queries, i)
+		if err != nil {
+			return Postings{}, false, false, err
+		}
 		if !ok {
-			d.indexDb.Set(key, id, 0)
+			exact = false
+			continue
+		}
+		lists = append(lists, p)
+		exact = exact && x
+	}
+
+	var excluded []Postings
+	for _, x := range e.Exprs {
+		if x.Op == query.BoolNot {
+			p, ok, xExact, err := d.postings(query.Expr{Op: query.BoolAnd, Queries: x.Queries, Exprs: x.Exprs})
+			if err != nil {
+				return Postings{}, false, false, err
+			}
+			if ok && xExact {
+				excluded = append(excluded, p)
+			} else {
+				exact = false
+			}
 			continue
 		}
-		ids, ok := v.(string)
+
+		p, ok, xExact, err := d.postings(x)
+		if err != nil {
+			return Postings{}, false, false, err
+		}
 		if !ok {
-			log.Printf("failed to add index: %s", id)
+			exact = false
 			continue
 		}
+		lists = append(lists, p)
+		exact = exact && xExact
+	}
+
+	if len(lists) == 0 {
+		if len(excluded) == 0 {
+			return Postings{}, false, false, nil
+		}
+		all, err := d.index.Lookup(documentTerm)
+		if err != nil {
+			log.Printf("failed to get data from index: %s", err)
+			return Postings{}, false, false, ErrFatal
+		}
+		lists = append(lists, all)
+	}
+
+	// Intersecting the shortest lists first keeps intermediate results small.
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Len() < lists[j].Len()
+	})
+	matched := lists[0]
+	for _, p := range lists[1:] {
+		matched = matched.And(p)
+	}
+	for _, p := range excluded {
+		matched = matched.AndNot(p)
+	}
+	return matched, true, exact, nil
+}
 
-		if !strings.Contains(id, ids) {
-			ids = fmt.Sprintf("%s,%s", ids, id)
-			d.indexDb.Set(key, ids, 0)
+func (d DocDB) orPostings(e query.Expr) (Postings, bool, bool, error) {
+	var matched Postings
+	exact := true
+	for i := range e.Queries {
+		p, ok, x, err := d.queryPostings(e.Queries, i)
+		if err != nil || !ok {
+			return Postings{}, false, false, err
 		}
+		matched = matched.Or(p)
+		exact = exact && x
 	}
+	for _, x := range e.Exprs {
+		p, ok, xExact, err := d.postings(x)
+		if err != nil || !ok {
+			return Postings{}, false, false, err
+		}
+		matched = matched.Or(p)
+		exact = exact && xExact
+	}
+	return matched, true, exact, nil
 }
 
-func (d DocDB) lookup(pv string) ([]string, error) {
-	b, ok := d.indexDb.Get(pv)
-	if !ok {
-		return nil, nil
+// queryPostings looks up the documents which may match qs[i], as postings
+// does.
+func (d DocDB) queryPostings(qs query.Queries, i int) (Postings, bool, bool, error) {
+	q := qs[i]
+	path := strings.Join(q.Keys, ".")
+	def := d.indexes.lookup(path)
+	if !def.Ready || def.Kind == IndexNone {
+		return Postings{}, false, false, nil
 	}
-	s, ok := b.(string)
-	if !ok {
-		log.Printf("failed to convert data in indexDB to string: %v", pv)
+
+	var p Postings
+	var err error
+	exact := false
+	switch {
+	case q.Op == query.OpeExists:
+		// Every indexed value is also kept under its path alone.
+		p, err = d.index.Lookup(path)
+		exact = true
+	case q.Op == query.OpeText:
+		if def.Kind != IndexText {
+			return Postings{}, false, false, nil
+		}
+		p, err = d.textPostings(path, q.Value)
+	case def.Kind == IndexText:
+		if q.Op == query.OpeEq {
+			return Postings{}, false, false, nil
+		}
+		p, err = d.index.Lookup(path)
+	case q.Op == query.OpeEq && q.Coerce:
+		p, err = d.looseLookup(path, q.Value)
+	case q.Op == query.OpeEq:
+		p, err = d.index.Lookup(path + "=" + typedKey(q.Literal()))
+		exact = true
+	case q.Op == query.OpeNe && !q.Coerce:
+		// Documents with the path, but without the value.
+		var eq Postings
+		if eq, err = d.index.Lookup(path + "=" + typedKey(q.Literal())); err == nil {
+			p, err = d.index.Lookup(path)
+			p = p.AndNot(eq)
+		}
+		exact = true
+	case (q.Op == query.OpeWildcard || q.Op == query.OpeRegex) && !q.Coerce:
+		prefix, ok := q.Prefix()
+		if !ok {
+			p, err = d.index.Lookup(path)
+			break
+		}
+		// Strings are quoted in terms, and those starting with the prefix
+		// have terms starting with the quoted prefix without its end quote.
+		key := strconv.Quote(prefix)
+		p, err = d.index.Prefix(path + "=" + key[:len(key)-1])
+	case def.Kind == IndexRange && !q.Coerce:
+		lo, hi, ok := q.Bounds()
+		if !ok {
+			p, err = d.index.Lookup(path)
+			break
+		}
+		p, err = d.rangeLookup(path, lo, hi)
+		exact = true
+	default:
+		p, err = d.index.Lookup(path)
+	}
+	if err != nil {
+		log.Printf("failed to get data from index: %v: %s", q, err)
+		return Postings{}, false, false, ErrFatal
+	}
+	return p, true, exact, nil
+}
+
+func (d DocDB) scan() ([]string, error) {
+	var ids []string
+	err := d.store.Iterate(func(id string, _ []byte) bool {
+		ids = append(ids, id)
+		return true
+	})
+	if err != nil {
+		log.Printf("failed to scan documents: %s", err)
 		return nil, ErrFatal
 	}
-	return strings.Split(s, ","), nil
+	sort.Strings(ids)
+	return ids, nil
 }
 
-func getPath(obj map[string]any, prefix string) []string {
-	var path []string
-	for k, v := range obj {
-		if prefix != "" {
-			k = prefix + "." + k
+func (d DocDB) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.done)
+	})
+	return d.store.Close()
+}
+
+func (d DocDB) indexDoc(id string, doc map[string]any) {
+	keys := make([]indexKey, 0)
+	for key := range d.indexKeys(doc) {
+		keys = append(keys, key)
+	}
+	d.setIndex(id, keys)
+	d.texts.set(id, d.textTokens(doc))
+}
+
+// reindexDoc updates the index from old to doc. Every key old could have been
+// indexed under is removed, so that keys left by a previous index definition
+// do not survive the update.
+func (d DocDB) reindexDoc(id string, old, doc map[string]any) {
+	staleKeys := d.allIndexKeys(old)
+	oldKeys := d.indexKeys(old)
+	newKeys := d.indexKeys(doc)
+
+	var removed, added []indexKey
+	for key := range staleKeys {
+		if !newKeys[key] {
+			removed = append(removed, key)
 		}
-		switch t := v.(type) {
-		case map[string]any:
-			path = append(path, getPath(t, k)...)
-			continue
-		case []any:
+	}
+	for key := range newKeys {
+		if !oldKeys[key] {
+			added = append(added, key)
+		}
+	}
+
+	d.removeIndex(id, removed)
+	d.setIndex(id, added)
+}
+
+// syncIndex brings the index of a document in line with the current index
+// definitions.
+func (d DocDB) syncIndex(id string, doc map[string]any) {
+	keys := d.indexKeys(doc)
+	d.texts.set(id, d.textTokens(doc))
+
+	var removed, added []indexKey
+	for key := range d.allIndexKeys(doc) {
+		if keys[key] {
+			added = append(added, key)
+		} else {
+			removed = append(removed, key)
+		}
+	}
+
+	d.removeIndex(id, removed)
+	d.setIndex(id, added)
+}
+
+func (d DocDB) setIndex(id string, keys []indexKey) {
+	for _, key := range keys {
+		var err error
+		if key.value != nil {
+			err = d.index.AddValue(key.path, key.value, id)
+		} else {
+			err = d.index.Add(key.term, id)
+		}
+		if err != nil {
+			log.Printf("failed to add index: %s: %s", id, err)
+		}
+	}
+}
+
+func (d DocDB) removeIndex(id string, keys []indexKey) {
+	for _, key := range keys {
+		var err error
+		if key.value != nil {
+			err = d.index.RemoveValue(key.path, key.value, id)
+		} else {
+			err = d.index.Remove(key.term, id)
+		}
+		if err != nil {
+			log.Printf("failed to remove index: %s: %s", id, err)
+		}
+	}
+}
+
+// indexKey is either a term, or an ordered value of a path when value is set.
+type indexKey struct {
+	term  string
+	path  string
+	value any
+}
+
+// documentTerm is the term every document is indexed under, so that NOT can be
+// taken from all documents. It is not valid UTF-8, so no path is the same.
+const documentTerm = "\xff"
+
+func (d DocDB) indexKeys(doc map[string]any) map[indexKey]bool {
+	keys := make(map[indexKey]bool)
+	if doc != nil {
+		keys[indexKey{term: documentTerm}] = true
+	}
+	leaves(doc, "", func(path string, v any) {
+		switch d.indexes.lookup(path).Kind {
+		case IndexNone:
+			return
+		case IndexText:
+			// Whole values of texts are not indexed, as they are rarely
+			// looked up and take up much memory.
+			keys[indexKey{term: path}] = true
+			addTextKeys(keys, path, v)
+			return
+		case IndexRange:
+			addOrderedKeys(keys, path, v)
+		}
+		addTermKeys(keys, path, v)
+	})
+	d.addCompoundKeys(keys, doc)
+	return keys
+}
+
+// allIndexKeys returns every key doc could be indexed under, whatever the
+// index definitions of its paths are.
+func (d DocDB) allIndexKeys(doc map[string]any) map[indexKey]bool {
+	keys := make(map[indexKey]bool)
+	if doc != nil {
+		keys[indexKey{term: documentTerm}] = true
+	}
+	leaves(doc, "", func(path string, v any) {
+		addOrderedKeys(keys, path, v)
+		addTextKeys(keys, path, v)
+		addTermKeys(keys, path, v)
+	})
+	d.addCompoundKeys(keys, doc)
+	return keys
+}
+
+func addTermKeys(keys map[indexKey]bool, path string, v any) {
+	keys[indexKey{term: path}] = true
+	keys[indexKey{term: path + "=" + typedKey(v)}] = true
+}
+
+// typedKey is the text of v in index keys. Values of different JSON types have
+// different keys, e.g. 1 and "1".
+func typedKey(v any) string {
+	if f, ok := toFloat(v); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(t)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+// looseLookup finds values of any JSON type which read as the value, for
+// coerced queries.
+func (d DocDB) looseLookup(path, value string) (Postings, error) {
+	keys := []string{strconv.Quote(value)}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		keys = append(keys, typedKey(f))
+	}
+	switch value {
+	case "true", "false", "null":
+		keys = append(keys, value)
+	}
+
+	var matched Postings
+	for _, key := range keys {
+		p, err := d.index.Lookup(path + "=" + key)
+		if err != nil {
+			return Postings{}, err
+		}
+		matched = matched.Or(p)
+	}
+	return matched, nil
+}
+
+func (d DocDB) rangeLookup(path string, lo, hi *query.Bound) (Postings, error) {
+	var bounds []*Bound
+	for _, b := range []*query.Bound{lo, hi} {
+		if b == nil {
+			bounds = append(bounds, nil)
 			continue
 		}
+		switch b.Value.(type) {
+		case float64, string:
+		default:
+			// Only numbers and strings are ordered.
+			return Postings{}, nil
+		}
+		bounds = append(bounds, &Bound{Value: b.Value, Inclusive: b.Inclusive})
+	}
+	return d.index.Range(path, bounds[0], bounds[1])
+}
+
+func addOrderedKeys(keys map[indexKey]bool, path string, v any) {
+	for _, ov := range orderedValues(v) {
+		keys[indexKey{path: path, value: ov}] = true
+	}
+}
+
+// orderedValues returns v as a float64 or a string, which are ordered
+// separately.
+func orderedValues(v any) []any {
+	if s, ok := v.(string); ok {
+		return []any{s}
+	}
+	if f, ok := toFloat(v); ok {
+		return []any{f}
+	}
+	return nil
+}
 
-		path = append(path, k)
+func toFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int8:
+		return float64(t), true
+	case int16:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint:
+		return float64(t), true
+	case uint8:
+		return float64(t), true
+	case uint16:
+		return float64(t), true
+	case uint32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
 	}
+	return 0, false
+}
 
-	return path
+// leaves visits the values of obj under each path which a query resolves to
+// them, as the planner trusts the index to agree with the query.
+func leaves(obj map[string]any, prefix string, fn func(path string, v any)) {
+	objectLeaves(obj, prefix, false, fn)
 }
 
-func getPathValues(obj map[string]any, prefix string) []string {
-	var pvs []string
+// objectLeaves visits the values of obj. inArray tells that obj is an element
+// visited under the path of its array, where a numeric key is read as a
+// position of the array, so such keys are skipped.
+func objectLeaves(obj map[string]any, prefix string, inArray bool, fn func(path string, v any)) {
 	for k, v := range obj {
+		if _, err := strconv.Atoi(k); inArray && err == nil {
+			continue
+		}
 		if prefix != "" {
 			k = prefix + "." + k
 		}
-		switch t := v.(type) {
-		case map[string]any:
-			pvs = append(pvs, getPathValues(t, k)...)
-			continue
-		case []any:
-			continue
-		}
+		valueLeaves(v, k, fn)
+	}
+}
 
-		pvs = append(pvs, fmt.Sprintf("%s=%v", k, v))
+func valueLeaves(v any, path string, fn func(path string, v any)) {
+	switch t := v.(type) {
+	case map[string]any:
+		objectLeaves(t, path, false, fn)
+	case []any:
+		arrayLeaves(t, path, fn)
+	default:
+		fn(path, v)
 	}
+}
+
+// arrayLeaves visits each element under its positional path, and under the
+// path of the array, so that any element matches.
+func arrayLeaves(arr []any, prefix string, fn func(path string, v any)) {
+	for i, v := range arr {
+		valueLeaves(v, prefix+"."+strconv.Itoa(i), fn)
+		elementLeaves(v, prefix, fn)
+	}
+}
+
+// elementLeaves visits an element under the path of its array. A nested array
+// is flattened without positions, as a position in the path is read as one of
+// the outer array.
+func elementLeaves(v any, prefix string, fn func(path string, v any)) {
+	switch t := v.(type) {
+	case map[string]any:
+		objectLeaves(t, prefix, true, fn)
+	case []any:
+		for _, e := range t {
+			elementLeaves(e, prefix, fn)
+		}
+	default:
+		fn(prefix, v)
+	}
+}
 
-	return pvs
+// NewDocDB is like Open, but panics if the database fails to be opened.
+func NewDocDB(opts ...Option) *DocDB {
+	d, err := Open(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return d
 }
 
-func NewDocDB() *DocDB {
-	return &DocDB{
-		db:      cache.New(30*time.Minute, 10*time.Minute),
-		indexDb: cache.New(30*time.Minute, 10*time.Minute),
+// Open opens the database over the documents and the index definitions of its
+// store. Index definitions given by WithIndexes replace stored ones of the same
+// path. It fails if a unique index is not kept by the stored documents, as the
+// index would not be enforced.
+func Open(opts ...Option) (*DocDB, error) {
+	o := options{
+		retention:      retention{versions: 10},
+		expiryInterval: 10 * time.Second,
 	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore()
+	}
+	if o.index == nil {
+		o.index = NewMemoryIndex()
+	}
+
+	saved, err := o.store.LoadIndexes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index definitions: %w", err)
+	}
+
+	d := &DocDB{
+		mu:        &sync.RWMutex{},
+		rev:       new(uint64),
+		store:     o.store,
+		index:     o.index,
+		indexes:   newIndexDefs(append(saved, o.indexes...)),
+		uniques:   newUniqueIndex(),
+		texts:     newTextIndex(),
+		idField:   o.idField,
+		retention: o.retention,
+		expiry:    newExpiryQueue(),
+		now:       time.Now,
+		done:      make(chan struct{}),
+		closeOnce: &sync.Once{},
+	}
+
+	*d.rev = d.store.Revision()
+	err = d.store.Iterate(func(id string, b []byte) bool {
+		r, doc, err := decodeRecord(b)
+		if err != nil {
+			log.Printf("failed to convert data to document: %s: %s", id, err)
+			return true
+		}
+		if r.Rev > *d.rev {
+			*d.rev = r.Rev
+		}
+		d.indexDoc(id, doc)
+		d.expiry.set(id, r.ExpiresAt)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild index: %w", err)
+	}
+	for _, def := range d.indexes.list() {
+		if err := d.constrain(def); err != nil {
+			return nil, fmt.Errorf("failed to enforce unique index on %s: %w", def.Path, err)
+		}
+	}
+
+	if o.expiryInterval > 0 {
+		go d.reapLoop(o.expiryInterval)
+	}
+
+	return d, nil
 }
@@ -1,13 +1,15 @@
 package docdb
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/x-color/docdb-in-go/query"
 )
 
-func Test_getPathValues(t *testing.T) {
+func Test_leaves(t *testing.T) {
 	type args struct {
 		obj    map[string]any
 		prefix string
@@ -36,15 +38,770 @@ func Test_getPathValues(t *testing.T) {
 				"a.b.d.e=1",
 			},
 		},
+		{
+			name: "Generate path and value set of array",
+			args: args{
+				obj: map[string]any{
+					"tags": []any{"go", "db"},
+					"authors": []any{
+						map[string]any{"name": "alice"},
+					},
+				},
+			},
+			want: []string{
+				`tags="go"`,
+				`tags.0="go"`,
+				`tags="db"`,
+				`tags.1="db"`,
+				`authors.name="alice"`,
+				`authors.0.name="alice"`,
+			},
+		},
+		{
+			name: "Generate path and value set of nested array",
+			args: args{
+				obj: map[string]any{
+					"m": []any{[]any{1, 2}},
+					"a": []any{map[string]any{"0": "z"}},
+				},
+			},
+			want: []string{
+				"m=1",
+				"m=2",
+				"m.0=1",
+				"m.0=2",
+				"m.0.0=1",
+				"m.0.1=2",
+				`a.0.0="z"`,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			sortOpt := cmpopts.SortSlices(func(x, y string) bool {
 				return x < y
 			})
-			got := getPathValues(tt.args.obj, tt.args.prefix)
+			var got []string
+			leaves(tt.args.obj, tt.args.prefix, func(k string, v any) {
+				got = append(got, k+"="+typedKey(v))
+			})
 			if diff := cmp.Diff(tt.want, got, sortOpt); diff != "" {
-				t.Errorf("getPathValues() mismatch (-want +got):\n%s", diff)
+				t.Errorf("leaves() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// lookup returns the ids of the documents indexed under the term.
+func (d DocDB) lookup(term string) ([]string, error) {
+	p, err := d.index.Lookup(term)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	p.Each(func(n uint32) bool {
+		if id, ok := d.index.Resolve(n); ok {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	return ids, nil
+}
+
+func TestDocDB_Update(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        map[string]any
+		update     map[string]any
+		wantLookup map[string][]string
+	}{
+		{
+			name: "Replace index entries",
+			doc: map[string]any{
+				"name": "bookA",
+				"detail": map[string]any{
+					"price": 100,
+				},
+			},
+			update: map[string]any{
+				"name": "bookB",
+				"tag":  "new",
+			},
+			wantLookup: map[string][]string{
+				`name="bookA"`:     nil,
+				"detail.price=100": nil,
+				"detail.price":     nil,
+				`name="bookB"`:     {"id"},
+				"name":             {"id"},
+				`tag="new"`:        {"id"},
+				"tag":              {"id"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB()
+			id, err := d.Add(tt.doc)
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+			if _, err := d.Update(id, tt.update); err != nil {
+				t.Fatalf("DocDB.Update() error = %v", err)
+			}
+
+			for key, want := range tt.wantLookup {
+				got, err := d.lookup(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range want {
+					want[i] = id
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("index %s mismatch (-want +got):\n%s", key, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDocDB_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		idField string
+		docs    []map[string]any
+		wantIDs []string
+		wantErr error
+	}{
+		{
+			name:    "Generate id",
+			idField: "",
+			docs: []map[string]any{
+				{"sku": "B-12"},
+			},
+		},
+		{
+			name:    "Take id from field",
+			idField: "sku",
+			docs: []map[string]any{
+				{"sku": "B-12"},
+				{"sku": float64(13)},
+			},
+			wantIDs: []string{"B-12", "13"},
+		},
+		{
+			name:    "Duplicate id",
+			idField: "sku",
+			docs: []map[string]any{
+				{"sku": "B-12"},
+				{"sku": "B-12"},
+			},
+			wantErr: ErrAlreadyExists,
+		},
+		{
+			name:    "Invalid id",
+			idField: "sku",
+			docs: []map[string]any{
+				{"sku": ""},
+			},
+			wantErr: ErrInvalidID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(WithIDField(tt.idField))
+			var ids []string
+			var err error
+			for _, doc := range tt.docs {
+				var id string
+				id, err = d.Add(doc)
+				if err != nil {
+					break
+				}
+				ids = append(ids, id)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DocDB.Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantIDs == nil {
+				return
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("DocDB.Add() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_Upsert(t *testing.T) {
+	tests := []struct {
+		name    string
+		idField string
+		doc     map[string]any
+		wantErr error
+	}{
+		{
+			name:    "Any field without id field",
+			idField: "",
+			doc:     map[string]any{"sku": "OTHER"},
+		},
+		{
+			name:    "Id field matches id",
+			idField: "sku",
+			doc:     map[string]any{"sku": "B-13"},
+		},
+		{
+			name:    "No id field",
+			idField: "sku",
+			doc:     map[string]any{"name": "bookA"},
+		},
+		{
+			name:    "Id field differs from id",
+			idField: "sku",
+			doc:     map[string]any{"sku": "OTHER"},
+			wantErr: ErrInvalidID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(WithIDField(tt.idField))
+			defer d.Close()
+			if _, _, err := d.Upsert("B-13", tt.doc); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DocDB.Upsert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := d.Begin().Upsert("B-13", tt.doc); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Tx.Upsert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDocDB_UpdateIfRevision(t *testing.T) {
+	tests := []struct {
+		name      string
+		updates   int
+		rev       func(first uint64) uint64
+		wantErr   error
+		wantPrice float64
+	}{
+		{
+			name:      "Update current revision",
+			rev:       func(first uint64) uint64 { return first },
+			wantPrice: 200,
+		},
+		{
+			name:      "Update stale revision",
+			updates:   1,
+			rev:       func(first uint64) uint64 { return first },
+			wantErr:   ErrConflict,
+			wantPrice: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB()
+			id, err := d.Add(map[string]any{"price": 100})
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+			_, first, err := d.GetWithRevision(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.updates; i++ {
+				if _, err := d.Update(id, map[string]any{"price": 100}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rev, err := d.UpdateIfRevision(id, map[string]any{"price": 200}, tt.rev(first))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DocDB.UpdateIfRevision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			doc, got, err := d.GetWithRevision(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc["price"] != tt.wantPrice {
+				t.Errorf("DocDB.UpdateIfRevision() price = %v, want %v", doc["price"], tt.wantPrice)
+			}
+			if tt.wantErr == nil && (got != rev || rev <= first) {
+				t.Errorf("DocDB.UpdateIfRevision() revision = %v, stored %v, previous %v", rev, got, first)
+			}
+		})
+	}
+}
+
+func TestDocDB_Search_range(t *testing.T) {
+	d := NewDocDB(WithIndexes(
+		IndexDefinition{Path: "detail.price", Kind: IndexRange},
+		IndexDefinition{Path: "name", Kind: IndexRange},
+	))
+	defer d.Close()
+	docs := map[string]map[string]any{
+		"a": {"name": "bookA", "detail": map[string]any{"price": 100}},
+		"b": {"name": "bookB", "detail": map[string]any{"price": 200}},
+		"c": {"name": "bookC", "detail": map[string]any{"price": "300"}},
+		"d": {"name": "bookD", "detail": map[string]any{"price": "unknown"}},
+	}
+	for id, doc := range docs {
+		if _, _, err := d.Upsert(id, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Update("a", map[string]any{"name": "bookA", "detail": map[string]any{"price": 400}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		q              string
+		wantCandidates []string
+		wantIDs        []string
+	}{
+		{
+			name:           "Greater than number",
+			q:              "detail.price:>150",
+			wantCandidates: []string{"b", "a"},
+			wantIDs:        []string{"b", "a"},
+		},
+		{
+			name:           "Greater than numeric string",
+			q:              `detail.price:>"150"`,
+			wantCandidates: []string{"c", "d"},
+			wantIDs:        []string{"c", "d"},
+		},
+		{
+			name:           "Greater than or equal to number",
+			q:              "detail.price:>=200",
+			wantCandidates: []string{"b", "a"},
+			wantIDs:        []string{"b", "a"},
+		},
+		{
+			name:           "Number between bounds",
+			q:              "detail.price:[200 TO 400}",
+			wantCandidates: []string{"b"},
+			wantIDs:        []string{"b"},
+		},
+		{
+			name:           "Not equal",
+			q:              "name:!=bookA",
+			wantCandidates: []string{"b", "c", "d"},
+			wantIDs:        []string{"b", "c", "d"},
+		},
+		{
+			name:           "Less than number",
+			q:              "detail.price:<300",
+			wantCandidates: []string{"b"},
+			wantIDs:        []string{"b"},
+		},
+		{
+			name:           "Less than string",
+			q:              "name:<bookC",
+			wantCandidates: []string{"a", "b"},
+			wantIDs:        []string{"a", "b"},
+		},
+		{
+			name:           "Range and equality",
+			q:              `detail.price:>150 name:"bookB"`,
+			wantCandidates: []string{"b"},
+			wantIDs:        []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			candidates, err := d.candidates(qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantCandidates, candidates, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("DocDB.candidates() mismatch (-want +got):\n%s", diff)
+			}
+
+			res, err := d.Search(qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0)
+			for _, r := range res {
+				ids = append(ids, r["id"].(string))
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("DocDB.Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_Search_types(t *testing.T) {
+	d := NewDocDB()
+	defer d.Close()
+	docs := map[string]map[string]any{
+		"a": {"v": 1},
+		"b": {"v": "1"},
+		"c": {"v": true},
+		"d": {"v": "true"},
+		"e": {"v": nil},
+	}
+	for id, doc := range docs {
+		if _, _, err := d.Upsert(id, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		q              string
+		coerce         bool
+		wantCandidates []string
+	}{
+		{
+			name:           "Number",
+			q:              "v:1",
+			wantCandidates: []string{"a"},
+		},
+		{
+			name:           "String",
+			q:              `v:"1"`,
+			wantCandidates: []string{"b"},
+		},
+		{
+			name:           "Bool",
+			q:              "v:true",
+			wantCandidates: []string{"c"},
+		},
+		{
+			name:           "Null",
+			q:              "v:null",
+			wantCandidates: []string{"e"},
+		},
+		{
+			name:           "Coerced number",
+			q:              "v:1",
+			coerce:         true,
+			wantCandidates: []string{"a", "b"},
+		},
+		{
+			name:           "Coerced string",
+			q:              `v:"true"`,
+			coerce:         true,
+			wantCandidates: []string{"c", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.coerce {
+				qs = qs.Coerce()
+			}
+			candidates, err := d.candidates(qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantCandidates, candidates, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("DocDB.candidates() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_Search_bool(t *testing.T) {
+	d := NewDocDB(WithIndexes(
+		IndexDefinition{Path: "price", Kind: IndexRange},
+		IndexDefinition{Path: "note", Kind: IndexNone},
+	))
+	defer d.Close()
+	docs := map[string]map[string]any{
+		"a": {"status": "draft", "price": 50},
+		"b": {"status": "review", "price": 150},
+		"c": {"status": "published", "price": 80},
+		"d": {"status": "draft", "price": 200, "note": "x"},
+		"e": {"x": 1, "m": []any{[]any{1, 2}}},
+		"f": {"x": 1, "a": []any{map[string]any{"0": "z"}}},
+	}
+	for id, doc := range docs {
+		if _, _, err := d.Upsert(id, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		q              string
+		wantCandidates []string
+		wantIDs        []string
+	}{
+		{
+			name:           "Union of indexed queries",
+			q:              "status:draft OR status:review",
+			wantCandidates: []string{"a", "b", "d"},
+			wantIDs:        []string{"a", "b", "d"},
+		},
+		{
+			name:           "Difference of indexed queries",
+			q:              "price:<100 NOT status:draft",
+			wantCandidates: []string{"c"},
+			wantIDs:        []string{"c"},
+		},
+		{
+			name:           "Negated unindexed query is left to match",
+			q:              "price:>100 NOT note:x",
+			wantCandidates: []string{"b", "d"},
+			wantIDs:        []string{"b"},
+		},
+		{
+			name:           "Union with unindexed query scans",
+			q:              "status:draft OR note:x",
+			wantCandidates: []string{"a", "b", "c", "d", "e", "f"},
+			wantIDs:        []string{"a", "d"},
+		},
+		{
+			name:           "Intersection with union",
+			q:              "(status:draft OR status:review) AND price:>100",
+			wantCandidates: []string{"b", "d"},
+			wantIDs:        []string{"b", "d"},
+		},
+		{
+			name:           "Negation alone is taken from all documents",
+			q:              "NOT status:draft",
+			wantCandidates: []string{"b", "c", "e", "f"},
+			wantIDs:        []string{"b", "c", "e", "f"},
+		},
+		{
+			name:           "Difference with position of nested array",
+			q:              "x:1 NOT m.1:2",
+			wantCandidates: []string{"e", "f"},
+			wantIDs:        []string{"e", "f"},
+		},
+		{
+			name:           "Difference with numeric key in array",
+			q:              `x:1 NOT a.0:"z"`,
+			wantCandidates: []string{"e", "f"},
+			wantIDs:        []string{"e", "f"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sortStrings := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+			candidates, err := d.candidates(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantCandidates, candidates, sortStrings); diff != "" {
+				t.Errorf("DocDB.candidates() mismatch (-want +got):\n%s", diff)
+			}
+
+			res, err := d.Search(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0)
+			for _, r := range res {
+				ids = append(ids, r["id"].(string))
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids, sortStrings); diff != "" {
+				t.Errorf("DocDB.Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_Search_pattern(t *testing.T) {
+	d := NewDocDB(WithIndexes(IndexDefinition{Path: "note", Kind: IndexNone}))
+	defer d.Close()
+	docs := map[string]map[string]any{
+		"a": {"name": "bookA", "sku": "AB-123"},
+		"b": {"name": "bookD", "sku": "B-12"},
+		"c": {"name": "notebook", "sku": "XB-129"},
+		"d": {"name": "boat", "note": "bookA"},
+		"e": {"name": 100},
+	}
+	for id, doc := range docs {
+		if _, _, err := d.Upsert(id, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		q              string
+		coerce         bool
+		wantCandidates []string
+		wantIDs        []string
+	}{
+		{
+			name:           "Prefix",
+			q:              "name:book*",
+			wantCandidates: []string{"a", "b"},
+			wantIDs:        []string{"a", "b"},
+		},
+		{
+			name:           "Wildcards after prefix",
+			q:              "name:bo?k*",
+			wantCandidates: []string{"a", "b", "d"},
+			wantIDs:        []string{"a", "b"},
+		},
+		{
+			name:           "Leading wildcard is looked up by path",
+			q:              "sku:?B-12*",
+			wantCandidates: []string{"a", "b", "c"},
+			wantIDs:        []string{"a", "c"},
+		},
+		{
+			name:           "Anchored regex",
+			q:              "name:/^book[A-C]$/",
+			wantCandidates: []string{"a", "b"},
+			wantIDs:        []string{"a"},
+		},
+		{
+			name:           "Unanchored regex",
+			q:              "name:/book/",
+			wantCandidates: []string{"a", "b", "c", "d", "e"},
+			wantIDs:        []string{"a", "b", "c"},
+		},
+		{
+			name:           "Unindexed path scans",
+			q:              "note:book*",
+			wantCandidates: []string{"a", "b", "c", "d", "e"},
+			wantIDs:        []string{"d"},
+		},
+		{
+			name:           "Coerced prefix matches numbers",
+			q:              "name:1*",
+			coerce:         true,
+			wantCandidates: []string{"a", "b", "c", "d", "e"},
+			wantIDs:        []string{"e"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.coerce {
+				e = e.Coerce()
+			}
+			sortStrings := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+			candidates, err := d.candidates(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantCandidates, candidates, sortStrings); diff != "" {
+				t.Errorf("DocDB.candidates() mismatch (-want +got):\n%s", diff)
+			}
+
+			res, err := d.Search(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0)
+			for _, r := range res {
+				ids = append(ids, r["id"].(string))
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids, sortStrings); diff != "" {
+				t.Errorf("DocDB.Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_Search_lists(t *testing.T) {
+	d := NewDocDB(WithIndexes(IndexDefinition{Path: "note", Kind: IndexNone}))
+	defer d.Close()
+	docs := map[string]map[string]any{
+		"a": {"status": "draft", "detail": map[string]any{"price": 100}},
+		"b": {"status": "review", "detail": map[string]any{"price": nil}},
+		"c": {"status": "published", "detail": map[string]any{}},
+		"d": {"status": "draft", "note": "x"},
+		"e": {"note": "y"},
+	}
+	for id, doc := range docs {
+		if _, _, err := d.Upsert(id, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		q              string
+		wantCandidates []string
+		wantIDs        []string
+	}{
+		{
+			name:           "Any of values",
+			q:              `status:in("draft", "review")`,
+			wantCandidates: []string{"a", "b", "d"},
+			wantIDs:        []string{"a", "b", "d"},
+		},
+		{
+			name:           "Existence",
+			q:              "has:detail.price",
+			wantCandidates: []string{"a", "b"},
+			wantIDs:        []string{"a", "b"},
+		},
+		{
+			name:           "Missing with indexed query",
+			q:              "status:in(draft, published) missing:detail.price",
+			wantCandidates: []string{"c", "d"},
+			wantIDs:        []string{"c", "d"},
+		},
+		{
+			name:           "Missing alone is taken from all documents",
+			q:              "missing:detail.price",
+			wantCandidates: []string{"c", "d", "e"},
+			wantIDs:        []string{"c", "d", "e"},
+		},
+		{
+			name:           "Existence of unindexed path scans",
+			q:              "has:note",
+			wantCandidates: []string{"a", "b", "c", "d", "e"},
+			wantIDs:        []string{"d", "e"},
+		},
+		{
+			name:           "Missing of unindexed path scans",
+			q:              "missing:note",
+			wantCandidates: []string{"a", "b", "c", "d", "e"},
+			wantIDs:        []string{"a", "b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sortStrings := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+			candidates, err := d.candidates(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantCandidates, candidates, sortStrings); diff != "" {
+				t.Errorf("DocDB.candidates() mismatch (-want +got):\n%s", diff)
+			}
+
+			res, err := d.Search(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0)
+			for _, r := range res {
+				ids = append(ids, r["id"].(string))
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids, sortStrings); diff != "" {
+				t.Errorf("DocDB.Search() mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
@@ -0,0 +1,182 @@
+package docdb
+
+import (
+	"container/heap"
+	"fmt"
+	"log"
+	"time"
+)
+
+const expiresAtField = "_expiresAt"
+
+// Stats counts documents. Documents and Expiring leave out documents which
+// have expired but are not yet removed, as they can no longer be read.
+type Stats struct {
+	Documents int    `json:"documents"`
+	Expiring  int    `json:"expiring"`
+	Expired   uint64 `json:"expired"`
+}
+
+type expiryItem struct {
+	id string
+	at time.Time
+}
+
+type expiryHeap []expiryItem
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x any)        { *h = append(*h, x.(expiryItem)) }
+func (h *expiryHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// expiryQueue is not safe for concurrent use; DocDB guards it with its lock.
+// Entries in the heap are not removed when a deadline changes. Instead, an
+// entry is skipped when it no longer matches the deadline of its document.
+type expiryQueue struct {
+	heap      expiryHeap
+	deadlines map[string]time.Time
+	expired   uint64
+}
+
+func (q *expiryQueue) set(id string, at *time.Time) {
+	if at == nil {
+		delete(q.deadlines, id)
+		return
+	}
+	if cur, ok := q.deadlines[id]; ok && cur.Equal(*at) {
+		return
+	}
+	q.deadlines[id] = *at
+	heap.Push(&q.heap, expiryItem{id: id, at: *at})
+}
+
+func (q *expiryQueue) due(now time.Time) []string {
+	var ids []string
+	for q.heap.Len() > 0 && !q.heap[0].at.After(now) {
+		item := heap.Pop(&q.heap).(expiryItem)
+		if at, ok := q.deadlines[item.id]; ok && at.Equal(item.at) {
+			ids = append(ids, item.id)
+		}
+	}
+	return ids
+}
+
+func newExpiryQueue() *expiryQueue {
+	return &expiryQueue{
+		deadlines: map[string]time.Time{},
+	}
+}
+
+func (d DocDB) Stats() (Stats, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	count := 0
+	err := d.store.Iterate(func(string, []byte) bool {
+		count++
+		return true
+	})
+	if err != nil {
+		log.Printf("failed to count documents: %s", err)
+		return Stats{}, ErrFatal
+	}
+
+	now := d.now()
+	expiring := 0
+	for _, at := range d.expiry.deadlines {
+		if at.After(now) {
+			expiring++
+		} else {
+			count--
+		}
+	}
+
+	return Stats{
+		Documents: count,
+		Expiring:  expiring,
+		Expired:   d.expiry.expired,
+	}, nil
+}
+
+func (d DocDB) reap() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, id := range d.expiry.due(d.now()) {
+		r, doc, err := d.load(id)
+		if err != nil {
+			delete(d.expiry.deadlines, id)
+			continue
+		}
+		if !r.expired(d.now()) {
+			continue
+		}
+		if err := d.remove(id, r.Rev, doc); err != nil {
+			log.Printf("failed to remove expired document %s: %s", id, err)
+			continue
+		}
+		d.expiry.expired++
+	}
+}
+
+func (d DocDB) reapLoop(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			d.reap()
+		case <-d.done:
+			return
+		}
+	}
+}
+
+func (r record) expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !r.ExpiresAt.After(now)
+}
+
+func extractExpiry(doc map[string]any) (*time.Time, map[string]any, error) {
+	v, ok := doc[expiresAtField]
+	if !ok {
+		return nil, doc, nil
+	}
+
+	rest := make(map[string]any, len(doc)-1)
+	for k, v := range doc {
+		if k != expiresAtField {
+			rest[k] = v
+		}
+	}
+	if v == nil {
+		return nil, rest, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s must be an RFC3339 time", ErrInvalidExpiry, expiresAtField)
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidExpiry, err)
+	}
+	return &t, rest, nil
+}
+
+func withExpiry(doc map[string]any, at *time.Time) map[string]any {
+	if at == nil {
+		return doc
+	}
+	m := make(map[string]any, len(doc)+1)
+	for k, v := range doc {
+		m[k] = v
+	}
+	m[expiresAtField] = at.Format(time.RFC3339Nano)
+	return m
+}
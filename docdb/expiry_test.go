@@ -0,0 +1,160 @@
+package docdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDocDB_reap(t *testing.T) {
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		doc         map[string]any
+		patch       map[string]any
+		elapsed     time.Duration
+		wantExpired bool
+	}{
+		{
+			name:        "Document without expiry lives forever",
+			doc:         map[string]any{"name": "bookA"},
+			elapsed:     24 * 365 * time.Hour,
+			wantExpired: false,
+		},
+		{
+			name:        "Document expires",
+			doc:         map[string]any{"name": "bookA", "_expiresAt": "2022-01-01T01:00:00Z"},
+			elapsed:     time.Hour,
+			wantExpired: true,
+		},
+		{
+			name:        "Document does not expire before deadline",
+			doc:         map[string]any{"name": "bookA", "_expiresAt": "2022-01-01T01:00:00Z"},
+			elapsed:     time.Hour - time.Second,
+			wantExpired: false,
+		},
+		{
+			name:        "Expiry is kept by patch",
+			doc:         map[string]any{"name": "bookA", "_expiresAt": "2022-01-01T01:00:00Z"},
+			patch:       map[string]any{"name": "bookB"},
+			elapsed:     time.Hour,
+			wantExpired: true,
+		},
+		{
+			name:        "Expiry is removed by patch",
+			doc:         map[string]any{"name": "bookA", "_expiresAt": "2022-01-01T01:00:00Z"},
+			patch:       map[string]any{"_expiresAt": nil},
+			elapsed:     time.Hour,
+			wantExpired: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(WithExpiryInterval(0))
+			now := base
+			d.now = func() time.Time { return now }
+
+			id, err := d.Add(tt.doc)
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+			if tt.patch != nil {
+				if _, err := d.MergePatch(id, tt.patch); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			now = base.Add(tt.elapsed)
+			if _, err := d.Get(id); errors.Is(err, ErrNotFound) != tt.wantExpired {
+				t.Errorf("DocDB.Get() error = %v, wantExpired %v", err, tt.wantExpired)
+			}
+
+			stats, err := d.Stats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantDocs := 1
+			if tt.wantExpired {
+				wantDocs = 0
+			}
+			if stats.Documents != wantDocs {
+				t.Errorf("DocDB.Stats() before reaping = %+v, want %d documents", stats, wantDocs)
+			}
+
+			d.reap()
+			stats, err = d.Stats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids, err := d.lookup("name")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantExpired {
+				want := Stats{Documents: 0, Expiring: 0, Expired: 1}
+				if diff := cmp.Diff(want, stats); diff != "" {
+					t.Errorf("DocDB.Stats() mismatch (-want +got):\n%s", diff)
+				}
+				if len(ids) != 0 {
+					t.Errorf("expired document is left in index: %v", ids)
+				}
+				return
+			}
+			if stats.Documents != 1 || stats.Expired != 0 {
+				t.Errorf("DocDB.Stats() = %+v, want 1 document and no expired", stats)
+			}
+			if len(ids) != 1 {
+				t.Errorf("document is removed from index: %v", ids)
+			}
+		})
+	}
+}
+
+func Test_extractExpiry(t *testing.T) {
+	at := time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		doc     map[string]any
+		want    *time.Time
+		wantDoc map[string]any
+		wantErr error
+	}{
+		{
+			name:    "No expiry",
+			doc:     map[string]any{"a": "b"},
+			wantDoc: map[string]any{"a": "b"},
+		},
+		{
+			name:    "Expiry",
+			doc:     map[string]any{"a": "b", "_expiresAt": "2022-01-01T01:00:00Z"},
+			want:    &at,
+			wantDoc: map[string]any{"a": "b"},
+		},
+		{
+			name:    "Null expiry",
+			doc:     map[string]any{"a": "b", "_expiresAt": nil},
+			wantDoc: map[string]any{"a": "b"},
+		},
+		{
+			name:    "Invalid expiry",
+			doc:     map[string]any{"a": "b", "_expiresAt": "tomorrow"},
+			wantErr: ErrInvalidExpiry,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, doc, err := extractExpiry(tt.doc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("extractExpiry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("extractExpiry() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantDoc, doc); diff != "" {
+				t.Errorf("extractExpiry() document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package docdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const indexesFileName = "indexes.json"
+
+type FileStore struct {
+	mem  *MemoryStore
+	wal  *wal
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *FileStore) Get(id string) ([]byte, error) {
+	return s.mem.Get(id)
+}
+
+func (s *FileStore) Put(id string, doc []byte) error {
+	return s.wal.write(walRecord{Op: walOpPut, ID: id, Doc: doc}, func() {
+		s.mem.Put(id, doc)
+	})
+}
+
+func (s *FileStore) Delete(id string, rev uint64) error {
+	return s.wal.write(walRecord{Op: walOpDelete, ID: id, Rev: rev}, func() {
+		s.mem.Delete(id, rev)
+	})
+}
+
+func (s *FileStore) Iterate(fn func(id string, doc []byte) bool) error {
+	return s.mem.Iterate(fn)
+}
+
+func (s *FileStore) Batch(ops []BatchOp) error {
+	r := walRecord{Op: walOpBatch}
+	for _, op := range ops {
+		if op.Delete {
+			r.Ops = append(r.Ops, walRecord{Op: walOpDelete, ID: op.ID, Rev: op.Rev})
+			continue
+		}
+		r.Ops = append(r.Ops, walRecord{Op: walOpPut, ID: op.ID, Doc: op.Doc})
+	}
+	return s.wal.write(r, func() {
+		s.mem.Batch(ops)
+	})
+}
+
+// SaveIndexes replaces the file of index definitions at once, so that a crash
+// leaves either the old definitions or the new ones.
+func (s *FileStore) SaveIndexes(defs []IndexDefinition) error {
+	b, err := json.Marshal(defs)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.wal.dir, indexesFileName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.wal.dir, indexesFileName)); err != nil {
+		return err
+	}
+	return syncDir(s.wal.dir)
+}
+
+func (s *FileStore) LoadIndexes() ([]IndexDefinition, error) {
+	b, err := os.ReadFile(filepath.Join(s.wal.dir, indexesFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var defs []IndexDefinition
+	if err := json.Unmarshal(b, &defs); err != nil {
+		return nil, fmt.Errorf("broken index definitions: %w", err)
+	}
+	return defs, nil
+}
+
+func (s *FileStore) Revision() uint64 {
+	return s.mem.Revision()
+}
+
+func (s *FileStore) Snapshot() error {
+	return s.wal.snapshot(func() []walRecord {
+		records := []walRecord{{Op: walOpRev, Rev: s.mem.Revision()}}
+		s.mem.Iterate(func(id string, doc []byte) bool {
+			records = append(records, walRecord{Op: walOpPut, ID: id, Doc: doc})
+			return true
+		})
+		return records
+	})
+}
+
+func (s *FileStore) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		if err = s.Snapshot(); err != nil {
+			s.wal.close()
+			return
+		}
+		err = s.wal.close()
+	})
+	return err
+}
+
+func (s *FileStore) replay(r walRecord) error {
+	switch r.Op {
+	case walOpPut:
+		return s.mem.Put(r.ID, r.Doc)
+	case walOpDelete:
+		return s.mem.Delete(r.ID, r.Rev)
+	case walOpRev:
+		s.mem.keepRevision(r.Rev)
+		return nil
+	case walOpBatch:
+		for _, op := range r.Ops {
+			if err := s.replay(op); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation in wal: %s", r.Op)
+	}
+}
+
+func (s *FileStore) snapshotLoop(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.Snapshot(); err != nil {
+				log.Printf("failed to take snapshot: %s", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func NewFileStore(dir string, snapshotInterval time.Duration) (*FileStore, error) {
+	w, err := openWAL(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		mem:  NewMemoryStore(),
+		wal:  w,
+		done: make(chan struct{}),
+	}
+	if err := w.replay(s.replay); err != nil {
+		w.close()
+		return nil, err
+	}
+
+	if snapshotInterval > 0 {
+		go s.snapshotLoop(snapshotInterval)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,146 @@
+package docdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot bool
+	}{
+		{
+			name:     "Replay documents from wal",
+			snapshot: false,
+		},
+		{
+			name:     "Replay documents from snapshot",
+			snapshot: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := NewFileStore(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := NewDocDB(WithStore(s))
+			doc := map[string]any{
+				"name": "bookA",
+				"detail": map[string]any{
+					"price": float64(100),
+				},
+			}
+			id, err := d.Add(doc)
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+			deleted, err := d.Add(map[string]any{"name": "bookB"})
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+			if err := s.Batch([]BatchOp{{ID: deleted, Delete: true}}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.snapshot {
+				if err := s.Snapshot(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Simulate a crash by closing the log without a final snapshot.
+			if err := s.wal.close(); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err = NewFileStore(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d = NewDocDB(WithStore(s))
+			defer d.Close()
+
+			got, err := d.Get(id)
+			if err != nil {
+				t.Fatalf("can not get document: %v", err)
+			}
+			if diff := cmp.Diff(doc, got); diff != "" {
+				t.Errorf("document mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := d.Get(deleted); err != ErrNotFound {
+				t.Errorf("deleted document is replayed: %v", err)
+			}
+
+			ids, err := d.lookup("detail.price=100")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{id}, ids); diff != "" {
+				t.Errorf("index mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFileStore_revisionAfterDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot bool
+	}{
+		{
+			name:     "Replay revision from wal",
+			snapshot: false,
+		},
+		{
+			name:     "Replay revision from snapshot",
+			snapshot: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := NewFileStore(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := NewDocDB(WithStore(s))
+			for _, id := range []string{"a", "b", "a"} {
+				if _, _, err := d.Upsert(id, map[string]any{"name": id}); err != nil {
+					t.Fatalf("failed to add data to DB for preparing test: %v", err)
+				}
+			}
+			if err := d.Delete("a"); err != nil {
+				t.Fatal(err)
+			}
+			if tt.snapshot {
+				if err := s.Snapshot(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.wal.close(); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err = NewFileStore(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d = NewDocDB(WithStore(s))
+			defer d.Close()
+
+			rev, _, err := d.Upsert("a", map[string]any{"name": "a"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rev != 4 {
+				t.Errorf("DocDB.Upsert() rev = %d, want 4", rev)
+			}
+			if _, err := d.UpdateIfRevision("a", map[string]any{"name": "a"}, 3); !errors.Is(err, ErrConflict) {
+				t.Errorf("DocDB.UpdateIfRevision() error = %v, want %v", err, ErrConflict)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package docdb
+
+import (
+	"log"
+	"time"
+)
+
+type Version struct {
+	Rev       uint64         `json:"rev"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	Document  map[string]any `json:"document"`
+}
+
+type retention struct {
+	versions int
+	age      time.Duration
+}
+
+func (rt retention) apply(history []record, now time.Time) []record {
+	if rt.versions <= 0 {
+		return nil
+	}
+	if len(history) > rt.versions {
+		history = history[len(history)-rt.versions:]
+	}
+	if rt.age <= 0 {
+		return history
+	}
+
+	// A version is needed as long as it was current at some point within
+	// the retention period, that is, until the next version replaced it.
+	cutoff := now.Add(-rt.age)
+	for i := range history {
+		replacedAt := now
+		if i+1 < len(history) {
+			replacedAt = history[i+1].UpdatedAt
+		}
+		if !replacedAt.Before(cutoff) {
+			return history[i:]
+		}
+	}
+	return nil
+}
+
+// History returns the kept versions of the document, oldest first. The history
+// is kept in the document, so it is removed along with the document.
+func (d DocDB) History(id string) ([]Version, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	r, _, err := d.load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]Version, 0, len(r.History)+1)
+	for _, v := range append(r.History, r.version()) {
+		doc, err := v.document()
+		if err != nil {
+			log.Printf("failed to convert data to document: %s: %s", id, err)
+			return nil, ErrFatal
+		}
+		versions = append(versions, Version{
+			Rev:       v.Rev,
+			UpdatedAt: v.UpdatedAt,
+			Document:  doc,
+		})
+	}
+	return versions, nil
+}
+
+func (d DocDB) GetRevision(id string, rev uint64) (map[string]any, error) {
+	return d.findVersion(id, func(v Version) bool {
+		return v.Rev == rev
+	})
+}
+
+func (d DocDB) GetAsOf(id string, t time.Time) (map[string]any, uint64, error) {
+	var rev uint64
+	doc, err := d.findVersion(id, func(v Version) bool {
+		if v.UpdatedAt.After(t) {
+			return false
+		}
+		rev = v.Rev
+		return true
+	})
+	return doc, rev, err
+}
+
+func (d DocDB) Diff(id string, from, to uint64) ([]PatchOperation, error) {
+	versions, err := d.History(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var src, dst map[string]any
+	for _, v := range versions {
+		if v.Rev == from {
+			src = v.Document
+		}
+		if v.Rev == to {
+			dst = v.Document
+		}
+	}
+	if src == nil || dst == nil {
+		return nil, ErrNotFound
+	}
+
+	ops, err := diff(src, dst, "")
+	if err != nil {
+		log.Printf("failed to diff %s between %d and %d: %s", id, from, to, err)
+		return nil, ErrFatal
+	}
+	return ops, nil
+}
+
+// findVersion returns the newest version that satisfies fn.
+func (d DocDB) findVersion(id string, fn func(Version) bool) (map[string]any, error) {
+	versions, err := d.History(id)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if fn(versions[i]) {
+			return versions[i].Document, nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,167 @@
+package docdb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRetention_apply(t *testing.T) {
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []record{
+		{Rev: 1, UpdatedAt: base},
+		{Rev: 2, UpdatedAt: base.Add(1 * time.Hour)},
+		{Rev: 3, UpdatedAt: base.Add(2 * time.Hour)},
+	}
+	tests := []struct {
+		name     string
+		rt       retention
+		now      time.Time
+		wantRevs []uint64
+	}{
+		{
+			name:     "Keep all versions",
+			rt:       retention{versions: 10},
+			now:      base.Add(3 * time.Hour),
+			wantRevs: []uint64{1, 2, 3},
+		},
+		{
+			name:     "Keep latest versions",
+			rt:       retention{versions: 2},
+			now:      base.Add(3 * time.Hour),
+			wantRevs: []uint64{2, 3},
+		},
+		{
+			name:     "Drop versions replaced before retention period",
+			rt:       retention{versions: 10, age: 90 * time.Minute},
+			now:      base.Add(3 * time.Hour),
+			wantRevs: []uint64{2, 3},
+		},
+		{
+			name:     "Disable history",
+			rt:       retention{versions: 0},
+			now:      base.Add(3 * time.Hour),
+			wantRevs: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var revs []uint64
+			for _, r := range tt.rt.apply(append([]record{}, history...), tt.now) {
+				revs = append(revs, r.Rev)
+			}
+			if diff := cmp.Diff(tt.wantRevs, revs); diff != "" {
+				t.Errorf("retention.apply() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_History(t *testing.T) {
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDocDB()
+	now := base
+	d.now = func() time.Time { return now }
+
+	id, err := d.Add(map[string]any{"name": "bookA", "detail": map[string]any{"price": 100}})
+	if err != nil {
+		t.Fatalf("failed to add data to DB for preparing test: %v", err)
+	}
+	now = base.Add(24 * time.Hour)
+	if _, err := d.MergePatch(id, map[string]any{"detail": map[string]any{"price": 120}}); err != nil {
+		t.Fatal(err)
+	}
+	now = base.Add(48 * time.Hour)
+	if _, err := d.MergePatch(id, map[string]any{"name": nil, "tag": "sale"}); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := d.History(id)
+	if err != nil {
+		t.Fatalf("DocDB.History() error = %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("DocDB.History() returned %d versions, want 3", len(versions))
+	}
+	first, second, third := versions[0].Rev, versions[1].Rev, versions[2].Rev
+
+	t.Run("Get as of time", func(t *testing.T) {
+		doc, rev, err := d.GetAsOf(id, base.Add(30*time.Hour))
+		if err != nil {
+			t.Fatalf("DocDB.GetAsOf() error = %v", err)
+		}
+		want := map[string]any{"name": "bookA", "detail": map[string]any{"price": float64(120)}}
+		if diff := cmp.Diff(want, doc); diff != "" {
+			t.Errorf("DocDB.GetAsOf() mismatch (-want +got):\n%s", diff)
+		}
+		if rev != second {
+			t.Errorf("DocDB.GetAsOf() revision = %v, want %v", rev, second)
+		}
+		if _, _, err := d.GetAsOf(id, base.Add(-time.Hour)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DocDB.GetAsOf() before creation error = %v, want %v", err, ErrNotFound)
+		}
+	})
+
+	t.Run("Get revision", func(t *testing.T) {
+		doc, err := d.GetRevision(id, first)
+		if err != nil {
+			t.Fatalf("DocDB.GetRevision() error = %v", err)
+		}
+		want := map[string]any{"name": "bookA", "detail": map[string]any{"price": float64(100)}}
+		if diff := cmp.Diff(want, doc); diff != "" {
+			t.Errorf("DocDB.GetRevision() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Diff revisions", func(t *testing.T) {
+		ops, err := d.Diff(id, first, third)
+		if err != nil {
+			t.Fatalf("DocDB.Diff() error = %v", err)
+		}
+		want := []PatchOperation{
+			{Op: "remove", Path: "/name"},
+			{Op: "replace", Path: "/detail/price", Value: json.RawMessage("120")},
+			{Op: "add", Path: "/tag", Value: json.RawMessage(`"sale"`)},
+		}
+		if diff := cmp.Diff(want, ops); diff != "" {
+			t.Errorf("DocDB.Diff() mismatch (-want +got):\n%s", diff)
+		}
+
+		old, err := d.GetRevision(id, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patched, err := jsonPatch(old, ops)
+		if err != nil {
+			t.Fatal(err)
+		}
+		current, err := d.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(current, patched); diff != "" {
+			t.Errorf("patched document mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("History is removed with document", func(t *testing.T) {
+		if err := d.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.History(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DocDB.History() error = %v, want %v", err, ErrNotFound)
+		}
+		if _, err := d.GetRevision(id, first); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DocDB.GetRevision() error = %v, want %v", err, ErrNotFound)
+		}
+		if _, _, err := d.GetAsOf(id, base.Add(30*time.Hour)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DocDB.GetAsOf() error = %v, want %v", err, ErrNotFound)
+		}
+		if _, err := d.Diff(id, first, third); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DocDB.Diff() error = %v, want %v", err, ErrNotFound)
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package docdb
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"sync"
+)
+
+type Index interface {
+	Add(term, id string) error
+	Remove(term, id string) error
+	Lookup(term string) (Postings, error)
+	// Prefix returns the union of the postings of the terms which start with
+	// prefix.
+	Prefix(prefix string) (Postings, error)
+	Resolve(n uint32) (string, bool)
+
+	// AddValue, RemoveValue and Range maintain ordered values of a path. A
+	// value is either a float64 or a string, and each is ordered separately.
+	AddValue(path string, v any, id string) error
+	RemoveValue(path string, v any, id string) error
+	Range(path string, lo, hi *Bound) (Postings, error)
+}
+
+// Bound is an end of a range. Value is either a float64 or a string.
+type Bound struct {
+	Value     any
+	Inclusive bool
+}
+
+// Postings is an ordered set of document numbers stored as a bitmap.
+type Postings struct {
+	words []uint64
+}
+
+func (p *Postings) Add(n uint32) {
+	w := int(n / 64)
+	for len(p.words) <= w {
+		p.words = append(p.words, 0)
+	}
+	p.words[w] |= 1 << (n % 64)
+}
+
+func (p *Postings) Remove(n uint32) {
+	w := int(n / 64)
+	if w >= len(p.words) {
+		return
+	}
+	p.words[w] &^= 1 << (n % 64)
+	p.trim()
+}
+
+func (p Postings) Contains(n uint32) bool {
+	w := int(n / 64)
+	return w < len(p.words) && p.words[w]&(1<<(n%64)) != 0
+}
+
+func (p Postings) Len() int {
+	count := 0
+	for _, w := range p.words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+func (p Postings) Each(fn func(n uint32) bool) {
+	for i, w := range p.words {
+		for w != 0 {
+			b := bits.TrailingZeros64(w)
+			if !fn(uint32(i*64 + b)) {
+				return
+			}
+			w &^= 1 << b
+		}
+	}
+}
+
+func (p Postings) And(q Postings) Postings {
+	n := len(p.words)
+	if len(q.words) < n {
+		n = len(q.words)
+	}
+	r := Postings{words: make([]uint64, n)}
+	for i := 0; i < n; i++ {
+		r.words[i] = p.words[i] & q.words[i]
+	}
+	r.trim()
+	return r
+}
+
+func (p Postings) Or(q Postings) Postings {
+	if len(q.words) > len(p.words) {
+		p, q = q, p
+	}
+	r := p.Clone()
+	for i, w := range q.words {
+		r.words[i] |= w
+	}
+	return r
+}
+
+// AndNot returns the numbers in p which are not in q.
+func (p Postings) AndNot(q Postings) Postings {
+	r := p.Clone()
+	for i := 0; i < len(r.words) && i < len(q.words); i++ {
+		r.words[i] &^= q.words[i]
+	}
+	r.trim()
+	return r
+}
+
+func (p Postings) Clone() Postings {
+	return Postings{words: append([]uint64(nil), p.words...)}
+}
+
+func (p *Postings) trim() {
+	n := len(p.words)
+	for n > 0 && p.words[n-1] == 0 {
+		n--
+	}
+	p.words = p.words[:n]
+}
+
+type valueIndex struct {
+	nums *skipList[float64]
+	strs *skipList[string]
+}
+
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	terms  map[string]*Postings
+	values map[string]*valueIndex
+	nums   map[string]uint32
+	ids    []string
+	refs   []int
+	free   []uint32
+
+	// dict holds the terms in order, so that those with a prefix are in a
+	// row.
+	dict *skipList[string]
+}
+
+func (i *MemoryIndex) Add(term, id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	n := i.number(id)
+	p, ok := i.terms[term]
+	if !ok {
+		p = &Postings{}
+		i.terms[term] = p
+		i.dict.Insert(term, 0)
+	}
+	if !p.Contains(n) {
+		p.Add(n)
+		i.refs[n]++
+	}
+	return nil
+}
+
+func (i *MemoryIndex) Remove(term, id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	n, ok := i.nums[id]
+	if !ok {
+		return nil
+	}
+	p, ok := i.terms[term]
+	if !ok || !p.Contains(n) {
+		return nil
+	}
+	p.Remove(n)
+	if len(p.words) == 0 {
+		delete(i.terms, term)
+		i.dict.Delete(term, 0)
+	}
+	i.release(id, n)
+	return nil
+}
+
+func (i *MemoryIndex) AddValue(path string, v any, id string) error {
+	switch v.(type) {
+	case float64, string:
+	default:
+		return fmt.Errorf("unorderable value: %v", v)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	vi, ok := i.values[path]
+	if !ok {
+		vi = &valueIndex{nums: newSkipList[float64](), strs: newSkipList[string]()}
+		i.values[path] = vi
+	}
+
+	n := i.number(id)
+	var added bool
+	switch t := v.(type) {
+	case float64:
+		added = vi.nums.Insert(t, n)
+	case string:
+		added = vi.strs.Insert(t, n)
+	}
+	if added {
+		i.refs[n]++
+	}
+	return nil
+}
+
+func (i *MemoryIndex) RemoveValue(path string, v any, id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	n, ok := i.nums[id]
+	if !ok {
+		return nil
+	}
+	vi, ok := i.values[path]
+	if !ok {
+		return nil
+	}
+
+	var removed bool
+	switch t := v.(type) {
+	case float64:
+		removed = vi.nums.Delete(t, n)
+	case string:
+		removed = vi.strs.Delete(t, n)
+	}
+	if !removed {
+		return nil
+	}
+	if vi.nums.Len() == 0 && vi.strs.Len() == 0 {
+		delete(i.values, path)
+	}
+	i.release(id, n)
+	return nil
+}
+
+func (i *MemoryIndex) Range(path string, lo, hi *Bound) (Postings, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var p Postings
+	vi, ok := i.values[path]
+	if !ok {
+		return p, nil
+	}
+
+	var num, str bool
+	for _, b := range []*Bound{lo, hi} {
+		if b == nil {
+			continue
+		}
+		switch b.Value.(type) {
+		case float64:
+			num = true
+		case string:
+			str = true
+		default:
+			return Postings{}, fmt.Errorf("unorderable bound: %v", b.Value)
+		}
+	}
+	if num && str {
+		return Postings{}, fmt.Errorf("bounds of different types: %v, %v", lo.Value, hi.Value)
+	}
+
+	if !str {
+		var l, h *float64
+		if lo != nil {
+			v := lo.Value.(float64)
+			l = &v
+		}
+		if hi != nil {
+			v := hi.Value.(float64)
+			h = &v
+		}
+		vi.nums.Ascend(l, h, lo != nil && lo.Inclusive, hi != nil && hi.Inclusive, func(_ float64, n uint32) bool {
+			p.Add(n)
+			return true
+		})
+	}
+	if !num {
+		var l, h *string
+		if lo != nil {
+			v := lo.Value.(string)
+			l = &v
+		}
+		if hi != nil {
+			v := hi.Value.(string)
+			h = &v
+		}
+		vi.strs.Ascend(l, h, lo != nil && lo.Inclusive, hi != nil && hi.Inclusive, func(_ string, n uint32) bool {
+			p.Add(n)
+			return true
+		})
+	}
+	return p, nil
+}
+
+func (i *MemoryIndex) Lookup(term string) (Postings, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	p, ok := i.terms[term]
+	if !ok {
+		return Postings{}, nil
+	}
+	return p.Clone(), nil
+}
+
+func (i *MemoryIndex) Prefix(prefix string) (Postings, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var p Postings
+	i.dict.Ascend(&prefix, nil, true, false, func(term string, _ uint32) bool {
+		if !strings.HasPrefix(term, prefix) {
+			return false
+		}
+		p = p.Or(*i.terms[term])
+		return true
+	})
+	return p, nil
+}
+
+func (i *MemoryIndex) Resolve(n uint32) (string, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if int(n) >= len(i.ids) || i.ids[n] == "" {
+		return "", false
+	}
+	return i.ids[n], true
+}
+
+// release drops a reference to the number of the document, and releases the
+// number once nothing refers to the document.
+func (i *MemoryIndex) release(id string, n uint32) {
+	i.refs[n]--
+	if i.refs[n] == 0 {
+		delete(i.nums, id)
+		i.ids[n] = ""
+		i.free = append(i.free, n)
+	}
+}
+
+func (i *MemoryIndex) number(id string) uint32 {
+	if n, ok := i.nums[id]; ok {
+		return n
+	}
+
+	var n uint32
+	if len(i.free) > 0 {
+		n = i.free[len(i.free)-1]
+		i.free = i.free[:len(i.free)-1]
+		i.ids[n] = id
+	} else {
+		n = uint32(len(i.ids))
+		i.ids = append(i.ids, id)
+		i.refs = append(i.refs, 0)
+	}
+	i.nums[id] = n
+	return n
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		terms:  map[string]*Postings{},
+		dict:   newSkipList[string](),
+		values: map[string]*valueIndex{},
+		nums:   map[string]uint32{},
+	}
+}
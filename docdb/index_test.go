@@ -0,0 +1,355 @@
+package docdb
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPostings_And(t *testing.T) {
+	tests := []struct {
+		name string
+		p    []uint32
+		q    []uint32
+		want []uint32
+	}{
+		{
+			name: "Intersect postings",
+			p:    []uint32{1, 3, 64, 130},
+			q:    []uint32{3, 64, 65, 200},
+			want: []uint32{3, 64},
+		},
+		{
+			name: "Intersect disjoint postings",
+			p:    []uint32{1, 2},
+			q:    []uint32{300},
+			want: nil,
+		},
+		{
+			name: "Intersect with empty postings",
+			p:    []uint32{1, 2},
+			q:    nil,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPostings(tt.p...).And(newPostings(tt.q...))
+			if diff := cmp.Diff(tt.want, postingsNumbers(got)); diff != "" {
+				t.Errorf("Postings.And() mismatch (-want +got):\n%s", diff)
+			}
+			if got.Len() != len(tt.want) {
+				t.Errorf("Postings.Len() = %v, want %v", got.Len(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestPostings_Or(t *testing.T) {
+	tests := []struct {
+		name       string
+		p          []uint32
+		q          []uint32
+		wantOr     []uint32
+		wantAndNot []uint32
+	}{
+		{
+			name:       "Overlapping postings",
+			p:          []uint32{1, 3, 64, 130},
+			q:          []uint32{3, 64, 65, 200},
+			wantOr:     []uint32{1, 3, 64, 65, 130, 200},
+			wantAndNot: []uint32{1, 130},
+		},
+		{
+			name:       "Longer postings on the right",
+			p:          []uint32{1},
+			q:          []uint32{1, 300},
+			wantOr:     []uint32{1, 300},
+			wantAndNot: nil,
+		},
+		{
+			name:       "Empty postings",
+			p:          []uint32{1, 2},
+			q:          nil,
+			wantOr:     []uint32{1, 2},
+			wantAndNot: []uint32{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, q := newPostings(tt.p...), newPostings(tt.q...)
+			if diff := cmp.Diff(tt.wantOr, postingsNumbers(p.Or(q))); diff != "" {
+				t.Errorf("Postings.Or() mismatch (-want +got):\n%s", diff)
+			}
+			got := p.AndNot(q)
+			if diff := cmp.Diff(tt.wantAndNot, postingsNumbers(got)); diff != "" {
+				t.Errorf("Postings.AndNot() mismatch (-want +got):\n%s", diff)
+			}
+			if got.Len() != len(tt.wantAndNot) {
+				t.Errorf("Postings.Len() = %v, want %v", got.Len(), len(tt.wantAndNot))
+			}
+			if diff := cmp.Diff(tt.p, postingsNumbers(p)); tt.p != nil && diff != "" {
+				t.Errorf("Postings is modified (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemoryIndex_Remove(t *testing.T) {
+	tests := []struct {
+		name   string
+		ids    []string
+		remove string
+		want   []string
+	}{
+		{
+			name:   "Remove id from posting list",
+			ids:    []string{"a", "b", "c"},
+			remove: "b",
+			want:   []string{"a", "c"},
+		},
+		{
+			name:   "Remove last id",
+			ids:    []string{"a"},
+			remove: "a",
+			want:   nil,
+		},
+		{
+			name:   "Remove unknown id",
+			ids:    []string{"a"},
+			remove: "b",
+			want:   []string{"a"},
+		},
+		{
+			name:   "Add duplicated id",
+			ids:    []string{"a", "ab", "a", "b"},
+			remove: "b",
+			want:   []string{"a", "ab"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := NewMemoryIndex()
+			for _, id := range tt.ids {
+				if err := i.Add("key", id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := i.Remove("key", tt.remove); err != nil {
+				t.Fatal(err)
+			}
+			p, err := i.Lookup("key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range postingsNumbers(p) {
+				id, ok := i.Resolve(n)
+				if !ok {
+					t.Errorf("MemoryIndex.Resolve(%d) is not found", n)
+				}
+				got = append(got, id)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("MemoryIndex.Lookup() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemoryIndex_reuseNumber(t *testing.T) {
+	i := NewMemoryIndex()
+	for _, id := range []string{"a", "b"} {
+		if err := i.Add("key", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := i.Remove("key", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Add("other", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := i.Lookup("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range postingsNumbers(p) {
+		if id, _ := i.Resolve(n); id != "b" {
+			t.Errorf("MemoryIndex.Lookup() contains %s, want only b", id)
+		}
+	}
+	if len(i.ids) != 2 {
+		t.Errorf("document number is not reused: %v", i.ids)
+	}
+}
+
+func TestMemoryIndex_Range(t *testing.T) {
+	values := map[string][]any{
+		"a": {float64(100)},
+		"b": {float64(150), "150"},
+		"c": {float64(200)},
+		"d": {"apple"},
+		"e": {"banana"},
+	}
+	tests := []struct {
+		name    string
+		lo      *Bound
+		hi      *Bound
+		remove  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Greater than number",
+			lo:   &Bound{Value: float64(100)},
+			want: []string{"b", "c"},
+		},
+		{
+			name: "Greater than or equal to number",
+			lo:   &Bound{Value: float64(100), Inclusive: true},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "Between numbers",
+			lo:   &Bound{Value: float64(100)},
+			hi:   &Bound{Value: float64(200)},
+			want: []string{"b"},
+		},
+		{
+			name: "Less than string",
+			hi:   &Bound{Value: "b"},
+			want: []string{"b", "d"},
+		},
+		{
+			name:   "Removed value",
+			lo:     &Bound{Value: float64(100)},
+			remove: "c",
+			want:   []string{"b"},
+		},
+		{
+			name:    "Bounds of different types",
+			lo:      &Bound{Value: float64(100)},
+			hi:      &Bound{Value: "b"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := NewMemoryIndex()
+			for _, id := range []string{"a", "b", "c", "d", "e"} {
+				for _, v := range values[id] {
+					if err := i.AddValue("price", v, id); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if tt.remove != "" {
+				for _, v := range values[tt.remove] {
+					if err := i.RemoveValue("price", v, tt.remove); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			p, err := i.Range("price", tt.lo, tt.hi)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MemoryIndex.Range() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, n := range postingsNumbers(p) {
+				id, _ := i.Resolve(n)
+				got = append(got, id)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("MemoryIndex.Range() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemoryIndex_Prefix(t *testing.T) {
+	terms := map[string][]string{
+		"a": {`name="book"`},
+		"b": {`name="bookA"`, `name="notebook"`},
+		"c": {`name="boat"`},
+		"d": {`title="bookB"`},
+	}
+	tests := []struct {
+		name   string
+		prefix string
+		remove string
+		want   []string
+	}{
+		{
+			name:   "Terms with prefix",
+			prefix: `name="book`,
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "Whole term",
+			prefix: `name="boat"`,
+			want:   []string{"c"},
+		},
+		{
+			name:   "No terms with prefix",
+			prefix: `name="car`,
+		},
+		{
+			name:   "Removed term",
+			prefix: `name="book`,
+			remove: "a",
+			want:   []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := NewMemoryIndex()
+			for _, id := range []string{"a", "b", "c", "d"} {
+				for _, term := range terms[id] {
+					if err := i.Add(term, id); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if tt.remove != "" {
+				for _, term := range terms[tt.remove] {
+					if err := i.Remove(term, tt.remove); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			p, err := i.Prefix(tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range postingsNumbers(p) {
+				id, _ := i.Resolve(n)
+				got = append(got, id)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("MemoryIndex.Prefix() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func newPostings(ns ...uint32) Postings {
+	p := Postings{}
+	for _, n := range ns {
+		p.Add(n)
+	}
+	return p
+}
+
+func postingsNumbers(p Postings) []uint32 {
+	var ns []uint32
+	p.Each(func(n uint32) bool {
+		ns = append(ns, n)
+		return true
+	})
+	return ns
+}
@@ -0,0 +1,278 @@
+package docdb
+
+import (
+	"log"
+	"sort"
+	"strings"
+)
+
+type IndexKind string
+
+const (
+	IndexEquality IndexKind = "equality"
+	IndexRange    IndexKind = "range"
+	IndexNone     IndexKind = "none"
+	IndexUnique   IndexKind = "unique"
+	IndexText     IndexKind = "text"
+	IndexCompound IndexKind = "compound"
+)
+
+type IndexDefinition struct {
+	Path  string    `json:"path,omitempty"`
+	Paths []string  `json:"paths,omitempty"`
+	Kind  IndexKind `json:"kind"`
+	Ready bool      `json:"ready"`
+}
+
+func (def IndexDefinition) valid() bool {
+	switch def.Kind {
+	case IndexEquality, IndexRange, IndexNone, IndexUnique, IndexText:
+		return def.Path != "" && len(def.Paths) == 0
+	case IndexCompound:
+		if def.Path != "" || len(def.Paths) < 2 {
+			return false
+		}
+		for _, p := range def.Paths {
+			if p == "" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// name identifies the definition. Names of compound indexes start with a NUL,
+// so that they never clash with a path.
+func (def IndexDefinition) name() string {
+	if def.Kind == IndexCompound {
+		return "\x00" + strings.Join(def.Paths, "\x00")
+	}
+	return def.Path
+}
+
+// indexDefs holds the index definitions. A definition of a path applies to
+// the path and every path below it. Paths without a definition are indexed
+// for equality. It is guarded by DocDB.mu.
+type indexDefs struct {
+	defs      map[string]IndexDefinition
+	compounds map[string]IndexDefinition
+	gens      map[string]uint64
+	gen       uint64
+}
+
+func (c *indexDefs) define(def IndexDefinition) uint64 {
+	c.gen++
+	if def.Kind == IndexCompound {
+		c.compounds[def.name()] = def
+	} else {
+		c.defs[def.name()] = def
+	}
+	c.gens[def.name()] = c.gen
+	return c.gen
+}
+
+func (c *indexDefs) markReady(name string, gen uint64) {
+	if c.gens[name] != gen {
+		// The definition was replaced while it was built.
+		return
+	}
+	if def, ok := c.defs[name]; ok {
+		def.Ready = true
+		c.defs[name] = def
+	}
+	if def, ok := c.compounds[name]; ok {
+		def.Ready = true
+		c.compounds[name] = def
+	}
+}
+
+func (c *indexDefs) get(name string) (IndexDefinition, bool) {
+	if def, ok := c.defs[name]; ok {
+		return def, true
+	}
+	def, ok := c.compounds[name]
+	return def, ok
+}
+
+// with returns the definitions as they are once def is defined.
+func (c *indexDefs) with(def IndexDefinition) []IndexDefinition {
+	defs := []IndexDefinition{def}
+	for _, d := range c.list() {
+		if d.name() != def.name() {
+			defs = append(defs, d)
+		}
+	}
+	return defs
+}
+
+func (c *indexDefs) lookup(path string) IndexDefinition {
+	for p := path; ; {
+		if def, ok := c.defs[p]; ok {
+			return def
+		}
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return IndexDefinition{Path: path, Kind: IndexEquality, Ready: true}
+}
+
+func (c *indexDefs) compoundList() []IndexDefinition {
+	defs := make([]IndexDefinition, 0, len(c.compounds))
+	for _, def := range c.compounds {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].name() < defs[j].name()
+	})
+	return defs
+}
+
+func (c *indexDefs) list() []IndexDefinition {
+	defs := make([]IndexDefinition, 0, len(c.defs)+len(c.compounds))
+	for _, def := range c.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Path < defs[j].Path
+	})
+	return append(defs, c.compoundList()...)
+}
+
+func newIndexDefs(defs []IndexDefinition) *indexDefs {
+	c := &indexDefs{
+		defs:      map[string]IndexDefinition{},
+		compounds: map[string]IndexDefinition{},
+		gens:      map[string]uint64{},
+	}
+	for _, def := range defs {
+		if !def.valid() {
+			log.Printf("invalid index definition: %v", def)
+			continue
+		}
+		def.Ready = true
+		c.define(def)
+	}
+	return c
+}
+
+func WithIndexes(defs ...IndexDefinition) Option {
+	return func(o *options) {
+		o.indexes = append(o.indexes, defs...)
+	}
+}
+
+// CreateIndex defines how the path is indexed. The index is built over the
+// existing documents in the background; until it is ready, queries on the
+// path are answered by scanning documents. A unique index is enforced as soon
+// as it is created, and fails with a UniqueError if existing documents share
+// a value.
+func (d DocDB) CreateIndex(path string, kind IndexKind) error {
+	return d.createIndex(IndexDefinition{Path: path, Kind: kind})
+}
+
+// CreateCompoundIndex defines an index over the ordered paths. It is used by
+// queries with equalities on all but the last path, and an equality or a
+// range on the last path.
+func (d DocDB) CreateCompoundIndex(paths ...string) error {
+	return d.createIndex(IndexDefinition{Paths: paths, Kind: IndexCompound})
+}
+
+func (d DocDB) createIndex(def IndexDefinition) error {
+	if !def.valid() {
+		return ErrInvalidIndex
+	}
+
+	d.mu.Lock()
+	if err := d.constrain(def); err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	if err := d.saveIndexes(d.indexes.with(def)); err != nil {
+		// Enforce uniqueness as the previous definition does.
+		if prev, ok := d.indexes.get(def.name()); ok {
+			if err := d.constrain(prev); err != nil {
+				log.Printf("failed to restore unique index on %s: %s", prev.Path, err)
+			}
+		} else if def.Kind == IndexUnique {
+			d.uniques.drop(def.Path)
+		}
+		d.mu.Unlock()
+		return err
+	}
+	gen := d.indexes.define(def)
+	d.mu.Unlock()
+
+	go d.buildIndex(def.name(), gen)
+	return nil
+}
+
+// saveIndexes stores the definitions without whether they are ready, as
+// indexes are built when the database is opened.
+func (d DocDB) saveIndexes(defs []IndexDefinition) error {
+	for i := range defs {
+		defs[i].Ready = false
+	}
+	if err := d.store.SaveIndexes(defs); err != nil {
+		log.Printf("failed to store index definitions: %s", err)
+		return ErrFatal
+	}
+	return nil
+}
+
+// constrain starts or stops enforcing uniqueness of the path of def.
+func (d DocDB) constrain(def IndexDefinition) error {
+	if def.Kind == IndexCompound {
+		return nil
+	}
+	if def.Kind != IndexUnique {
+		d.uniques.drop(def.Path)
+		return nil
+	}
+
+	docs, err := d.liveDocuments()
+	if err != nil {
+		log.Printf("failed to get documents: %s", err)
+		return ErrFatal
+	}
+	return d.uniques.build(def.Path, docs)
+}
+
+func (d DocDB) Indexes() []IndexDefinition {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.indexes.list()
+}
+
+func (d DocDB) buildIndex(name string, gen uint64) {
+	var ids []string
+	err := d.store.Iterate(func(id string, _ []byte) bool {
+		ids = append(ids, id)
+		return true
+	})
+	if err != nil {
+		log.Printf("failed to build index on %q: %s", name, err)
+		return
+	}
+
+	for _, id := range ids {
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+
+		d.mu.Lock()
+		if _, doc, err := d.load(id); err == nil {
+			d.syncIndex(id, doc)
+		}
+		d.mu.Unlock()
+	}
+
+	d.mu.Lock()
+	d.indexes.markReady(name, gen)
+	d.mu.Unlock()
+}
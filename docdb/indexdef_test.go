@@ -0,0 +1,170 @@
+package docdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/x-color/docdb-in-go/query"
+)
+
+func waitIndexes(t *testing.T, d *DocDB) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		ready := true
+		for _, def := range d.Indexes() {
+			ready = ready && def.Ready
+		}
+		if ready {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("indexes are not built in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDocDB_CreateIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		defs    []IndexDefinition
+		update  map[string]any
+		term    string
+		wantIDs []string
+		wantErr error
+	}{
+		{
+			name:    "Paths are indexed for equality by default",
+			term:    `detail.description="this is sample book"`,
+			wantIDs: []string{"a"},
+		},
+		{
+			name:    "Index of existing documents is dropped",
+			defs:    []IndexDefinition{{Path: "detail.description", Kind: IndexNone}},
+			term:    `detail.description="this is sample book"`,
+			wantIDs: nil,
+		},
+		{
+			name:    "Definition applies to sub paths",
+			defs:    []IndexDefinition{{Path: "detail", Kind: IndexNone}},
+			term:    "detail.price=100",
+			wantIDs: nil,
+		},
+		{
+			name:    "Index is built again for existing documents",
+			opts:    []Option{WithIndexes(IndexDefinition{Path: "detail", Kind: IndexNone})},
+			defs:    []IndexDefinition{{Path: "detail.price", Kind: IndexRange}},
+			term:    "detail.price=100",
+			wantIDs: []string{"a"},
+		},
+		{
+			name:    "Updated document is not indexed on unindexed path",
+			defs:    []IndexDefinition{{Path: "detail.description", Kind: IndexNone}},
+			update:  map[string]any{"detail": map[string]any{"description": "updated"}},
+			term:    `detail.description="updated"`,
+			wantIDs: nil,
+		},
+		{
+			name:    "Unknown kind",
+			defs:    []IndexDefinition{{Path: "name", Kind: "fulltext"}},
+			wantErr: ErrInvalidIndex,
+		},
+		{
+			name:    "Empty path",
+			defs:    []IndexDefinition{{Path: "", Kind: IndexNone}},
+			wantErr: ErrInvalidIndex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(tt.opts...)
+			defer d.Close()
+			doc := map[string]any{
+				"name": "bookA",
+				"detail": map[string]any{
+					"price":       100,
+					"description": "this is sample book",
+				},
+			}
+			if _, _, err := d.Upsert("a", doc); err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+
+			for _, def := range tt.defs {
+				if err := d.CreateIndex(def.Path, def.Kind); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("DocDB.CreateIndex() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			waitIndexes(t, d)
+
+			if tt.update != nil {
+				if _, err := d.Update("a", tt.update); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ids, err := d.lookup(tt.term)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("DocDB.lookup() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_Search_unindexed(t *testing.T) {
+	d := NewDocDB(WithIndexes(
+		IndexDefinition{Path: "name", Kind: IndexNone},
+		IndexDefinition{Path: "detail", Kind: IndexNone},
+	))
+	defer d.Close()
+	docs := map[string]map[string]any{
+		"a": {"name": "bookA", "detail": map[string]any{"price": 100}, "tags": []any{"go"}},
+		"b": {"name": "bookB", "detail": map[string]any{"price": 200}, "tags": []any{"go"}},
+		"c": {"name": "bookC", "detail": map[string]any{"price": 300}, "tags": []any{"db"}},
+	}
+	for id, doc := range docs {
+		if _, _, err := d.Upsert(id, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		q       string
+		wantIDs []string
+	}{
+		{name: "Full scan", q: "detail.price:>150", wantIDs: []string{"b", "c"}},
+		{name: "Full scan by equality", q: `name:"bookA"`, wantIDs: []string{"a"}},
+		{name: "Indexed and unindexed paths", q: `tags:"go" detail.price:>150`, wantIDs: []string{"b"}},
+		{name: "No match", q: `name:"bookD"`, wantIDs: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := d.Search(qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0)
+			for _, r := range res {
+				ids = append(ids, r["id"].(string))
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("DocDB.Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
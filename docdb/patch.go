@@ -0,0 +1,364 @@
+package docdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func mergePatch(target, patch map[string]any) map[string]any {
+	doc := make(map[string]any, len(target))
+	for k, v := range target {
+		doc[k] = v
+	}
+
+	for k, v := range patch {
+		switch t := v.(type) {
+		case nil:
+			delete(doc, k)
+		case map[string]any:
+			sub, ok := doc[k].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+			}
+			doc[k] = mergePatch(sub, t)
+		default:
+			doc[k] = v
+		}
+	}
+
+	return doc
+}
+
+func jsonPatch(target map[string]any, ops []PatchOperation) (map[string]any, error) {
+	var doc any = deepCopy(target)
+	for i, op := range ops {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	result, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: document must be an object", ErrInvalidPatch)
+	}
+	return result, nil
+}
+
+func applyOperation(doc any, op PatchOperation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "remove":
+		doc, _, err := pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return v, nil
+		}
+		doc, _, err := pointerRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.From != op.Path && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: can not move %s into its child", ErrInvalidPatch, op.From)
+		}
+		doc, v, err := pointerRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopy(v))
+	case "test":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrTestFailed, err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			return nil, fmt.Errorf("%w: value at %s is not %s", ErrTestFailed, op.Path, op.Value)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+func (op PatchOperation) value() (any, error) {
+	if len(op.Value) == 0 {
+		return nil, fmt.Errorf("%w: %s operation requires value", ErrInvalidPatch, op.Op)
+	}
+	var v any
+	if err := json.Unmarshal(op.Value, &v); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+	return v, nil
+}
+
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("%w: invalid path %q", ErrInvalidPatch, p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(node any, path []string) (any, error) {
+	for _, tok := range path {
+		switch t := node.(type) {
+		case map[string]any:
+			v, ok := t[tok]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q is not found", ErrInvalidPatch, tok)
+			}
+			node = v
+		case []any:
+			i, err := arrayIndex(tok, len(t)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = t[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is not found", ErrInvalidPatch, tok)
+		}
+	}
+	return node, nil
+}
+
+func pointerAdd(node any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	tok := path[0]
+
+	switch t := node.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			t[tok] = v
+			return t, nil
+		}
+		child, ok := t[tok]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not found", ErrInvalidPatch, tok)
+		}
+		child, err := pointerAdd(child, path[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		t[tok] = child
+		return t, nil
+	case []any:
+		if len(path) == 1 {
+			i := len(t)
+			if tok != "-" {
+				var err error
+				i, err = arrayIndex(tok, len(t))
+				if err != nil {
+					return nil, err
+				}
+			}
+			t = append(t, nil)
+			copy(t[i+1:], t[i:])
+			t[i] = v
+			return t, nil
+		}
+		i, err := arrayIndex(tok, len(t)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := pointerAdd(t[i], path[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		t[i] = child
+		return t, nil
+	default:
+		return nil, fmt.Errorf("%w: %q is not found", ErrInvalidPatch, tok)
+	}
+}
+
+func pointerRemove(node any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: can not remove whole document", ErrInvalidPatch)
+	}
+	tok := path[0]
+
+	switch t := node.(type) {
+	case map[string]any:
+		child, ok := t[tok]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %q is not found", ErrInvalidPatch, tok)
+		}
+		if len(path) == 1 {
+			delete(t, tok)
+			return t, child, nil
+		}
+		child, removed, err := pointerRemove(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		t[tok] = child
+		return t, removed, nil
+	case []any:
+		i, err := arrayIndex(tok, len(t)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := t[i]
+			return append(t[:i], t[i+1:]...), removed, nil
+		}
+		child, removed, err := pointerRemove(t[i], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		t[i] = child
+		return t, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q is not found", ErrInvalidPatch, tok)
+	}
+}
+
+func arrayIndex(tok string, last int) (int, error) {
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, tok)
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || i > last {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, tok)
+	}
+	return i, nil
+}
+
+func deepCopy(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, v := range t {
+			m[k] = deepCopy(v)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, v := range t {
+			s[i] = deepCopy(v)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+func diff(src, dst map[string]any, prefix string) ([]PatchOperation, error) {
+	ops := make([]PatchOperation, 0)
+	for _, k := range sortedKeys(src) {
+		if _, ok := dst[k]; !ok {
+			ops = append(ops, PatchOperation{Op: "remove", Path: prefix + "/" + escapePointer(k)})
+		}
+	}
+	for _, k := range sortedKeys(dst) {
+		path := prefix + "/" + escapePointer(k)
+		v, ok := src[k]
+		if !ok {
+			op, err := newValueOperation("add", path, dst[k])
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, op)
+			continue
+		}
+		if reflect.DeepEqual(v, dst[k]) {
+			continue
+		}
+
+		sub, srcOk := v.(map[string]any)
+		dsub, dstOk := dst[k].(map[string]any)
+		if srcOk && dstOk {
+			subOps, err := diff(sub, dsub, path)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, subOps...)
+			continue
+		}
+		op, err := newValueOperation("replace", path, dst[k])
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func newValueOperation(op, path string, v any) (PatchOperation, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return PatchOperation{}, err
+	}
+	return PatchOperation{Op: op, Path: path, Value: b}, nil
+}
+
+func escapePointer(k string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1")
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,194 @@
+package docdb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_mergePatch(t *testing.T) {
+	type args struct {
+		target map[string]any
+		patch  map[string]any
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string]any
+	}{
+		{
+			name: "Replace value",
+			args: args{
+				target: map[string]any{"a": "b"},
+				patch:  map[string]any{"a": "c"},
+			},
+			want: map[string]any{"a": "c"},
+		},
+		{
+			name: "Add value",
+			args: args{
+				target: map[string]any{"a": "b"},
+				patch:  map[string]any{"b": "c"},
+			},
+			want: map[string]any{"a": "b", "b": "c"},
+		},
+		{
+			name: "Delete value by null",
+			args: args{
+				target: map[string]any{"a": "b", "b": "c"},
+				patch:  map[string]any{"a": nil},
+			},
+			want: map[string]any{"b": "c"},
+		},
+		{
+			name: "Merge nested object",
+			args: args{
+				target: map[string]any{
+					"detail": map[string]any{"price": 100, "description": "book"},
+				},
+				patch: map[string]any{
+					"detail": map[string]any{"price": 200, "description": nil},
+				},
+			},
+			want: map[string]any{
+				"detail": map[string]any{"price": 200},
+			},
+		},
+		{
+			name: "Replace non object with object",
+			args: args{
+				target: map[string]any{"a": "b"},
+				patch:  map[string]any{"a": map[string]any{"b": "c", "d": nil}},
+			},
+			want: map[string]any{"a": map[string]any{"b": "c"}},
+		},
+		{
+			name: "Replace array",
+			args: args{
+				target: map[string]any{"a": []any{"b"}},
+				patch:  map[string]any{"a": []any{"c", "d"}},
+			},
+			want: map[string]any{"a": []any{"c", "d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergePatch(tt.args.target, tt.args.patch)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mergePatch() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_jsonPatch(t *testing.T) {
+	type args struct {
+		target map[string]any
+		ops    string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]any
+		wantErr error
+	}{
+		{
+			name: "Add value",
+			args: args{
+				target: map[string]any{"a": map[string]any{}},
+				ops:    `[{"op":"add","path":"/a/b","value":1}]`,
+			},
+			want: map[string]any{"a": map[string]any{"b": float64(1)}},
+		},
+		{
+			name: "Add value to array",
+			args: args{
+				target: map[string]any{"a": []any{"x", "z"}},
+				ops:    `[{"op":"add","path":"/a/1","value":"y"},{"op":"add","path":"/a/-","value":"w"}]`,
+			},
+			want: map[string]any{"a": []any{"x", "y", "z", "w"}},
+		},
+		{
+			name: "Remove value",
+			args: args{
+				target: map[string]any{"a": "b", "c": []any{"x", "y"}},
+				ops:    `[{"op":"remove","path":"/a"},{"op":"remove","path":"/c/0"}]`,
+			},
+			want: map[string]any{"c": []any{"y"}},
+		},
+		{
+			name: "Replace value",
+			args: args{
+				target: map[string]any{"a/b": "c"},
+				ops:    `[{"op":"replace","path":"/a~1b","value":null}]`,
+			},
+			want: map[string]any{"a/b": nil},
+		},
+		{
+			name: "Move value",
+			args: args{
+				target: map[string]any{"a": map[string]any{"b": "c"}},
+				ops:    `[{"op":"move","from":"/a/b","path":"/d"}]`,
+			},
+			want: map[string]any{"a": map[string]any{}, "d": "c"},
+		},
+		{
+			name: "Copy value",
+			args: args{
+				target: map[string]any{"a": map[string]any{"b": "c"}},
+				ops:    `[{"op":"copy","from":"/a","path":"/d"}]`,
+			},
+			want: map[string]any{"a": map[string]any{"b": "c"}, "d": map[string]any{"b": "c"}},
+		},
+		{
+			name: "Test value then replace",
+			args: args{
+				target: map[string]any{"price": float64(100)},
+				ops:    `[{"op":"test","path":"/price","value":100},{"op":"replace","path":"/price","value":120}]`,
+			},
+			want: map[string]any{"price": float64(120)},
+		},
+		{
+			name: "Test failed",
+			args: args{
+				target: map[string]any{"price": float64(110)},
+				ops:    `[{"op":"test","path":"/price","value":100},{"op":"replace","path":"/price","value":120}]`,
+			},
+			wantErr: ErrTestFailed,
+		},
+		{
+			name: "Replace missing value",
+			args: args{
+				target: map[string]any{},
+				ops:    `[{"op":"replace","path":"/price","value":120}]`,
+			},
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name: "Unknown operation",
+			args: args{
+				target: map[string]any{},
+				ops:    `[{"op":"unknown","path":"/price"}]`,
+			},
+			wantErr: ErrInvalidPatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := make([]PatchOperation, 0)
+			if err := json.Unmarshal([]byte(tt.args.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+			got, err := jsonPatch(tt.args.target, ops)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("jsonPatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("jsonPatch() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package docdb
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type record struct {
+	Rev       uint64          `json:"rev"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"`
+	Doc       json.RawMessage `json:"doc"`
+	History   []record        `json:"history,omitempty"`
+}
+
+func (r record) encode(doc map[string]any) ([]byte, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	r.Doc = b
+	return json.Marshal(r)
+}
+
+func (r record) version() record {
+	return record{
+		Rev:       r.Rev,
+		UpdatedAt: r.UpdatedAt,
+		Doc:       r.Doc,
+	}
+}
+
+func decodeRecord(b []byte) (record, map[string]any, error) {
+	r := record{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return record{}, nil, err
+	}
+	doc, err := r.document()
+	if err != nil {
+		return record{}, nil, err
+	}
+	return r, doc, nil
+}
+
+func (r record) document() (map[string]any, error) {
+	doc := make(map[string]any)
+	if err := json.Unmarshal(r.Doc, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
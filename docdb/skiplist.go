@@ -0,0 +1,121 @@
+package docdb
+
+import "math/rand"
+
+const skipListMaxLevel = 24
+
+type ordered interface {
+	~float64 | ~string
+}
+
+type skipNode[K ordered] struct {
+	key  K
+	n    uint32
+	next []*skipNode[K]
+}
+
+func (x *skipNode[K]) less(key K, n uint32) bool {
+	return x.key < key || (x.key == key && x.n < n)
+}
+
+// skipList is a set of (key, document number) pairs ordered by key, then by
+// document number.
+type skipList[K ordered] struct {
+	head  *skipNode[K]
+	level int
+	len   int
+}
+
+func (l *skipList[K]) Insert(key K, n uint32) bool {
+	var update [skipListMaxLevel]*skipNode[K]
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].less(key, n) {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+	if next := x.next[0]; next != nil && next.key == key && next.n == n {
+		return false
+	}
+
+	level := randomLevel()
+	for i := l.level; i < level; i++ {
+		update[i] = l.head
+	}
+	if level > l.level {
+		l.level = level
+	}
+	node := &skipNode[K]{key: key, n: n, next: make([]*skipNode[K], level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	l.len++
+	return true
+}
+
+func (l *skipList[K]) Delete(key K, n uint32) bool {
+	var update [skipListMaxLevel]*skipNode[K]
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].less(key, n) {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+	node := x.next[0]
+	if node == nil || node.key != key || node.n != n {
+		return false
+	}
+
+	for i := 0; i < len(node.next); i++ {
+		update[i].next[i] = node.next[i]
+	}
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+	l.len--
+	return true
+}
+
+func (l *skipList[K]) Len() int {
+	return l.len
+}
+
+// Ascend calls fn for each pair with a key between lo and hi in order, until
+// fn returns false. A nil bound leaves that side of the range open.
+func (l *skipList[K]) Ascend(lo, hi *K, loInclusive, hiInclusive bool, fn func(key K, n uint32) bool) {
+	x := l.head
+	if lo != nil {
+		for i := l.level - 1; i >= 0; i-- {
+			for x.next[i] != nil && (x.next[i].key < *lo || (!loInclusive && x.next[i].key == *lo)) {
+				x = x.next[i]
+			}
+		}
+	}
+
+	for x = x.next[0]; x != nil; x = x.next[0] {
+		if hi != nil && (x.key > *hi || (!hiInclusive && x.key == *hi)) {
+			return
+		}
+		if !fn(x.key, x.n) {
+			return
+		}
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.Intn(4) == 0 {
+		level++
+	}
+	return level
+}
+
+func newSkipList[K ordered]() *skipList[K] {
+	return &skipList[K]{
+		head:  &skipNode[K]{next: make([]*skipNode[K], skipListMaxLevel)},
+		level: 1,
+	}
+}
@@ -0,0 +1,110 @@
+package docdb
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSkipList_Ascend(t *testing.T) {
+	type pair struct {
+		Key float64
+		N   uint32
+	}
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name        string
+		insert      []pair
+		delete      []pair
+		lo, hi      *float64
+		loInclusive bool
+		hiInclusive bool
+		want        []pair
+		wantLen     int
+	}{
+		{
+			name:    "Pairs are ordered by key and number",
+			insert:  []pair{{3, 1}, {1, 2}, {2, 4}, {2, 3}},
+			want:    []pair{{1, 2}, {2, 3}, {2, 4}, {3, 1}},
+			wantLen: 4,
+		},
+		{
+			name:    "Duplicated pair is inserted once",
+			insert:  []pair{{1, 1}, {1, 1}},
+			want:    []pair{{1, 1}},
+			wantLen: 1,
+		},
+		{
+			name:    "Exclusive bounds",
+			insert:  []pair{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
+			lo:      f(1),
+			hi:      f(4),
+			want:    []pair{{2, 2}, {3, 3}},
+			wantLen: 4,
+		},
+		{
+			name:        "Inclusive bounds",
+			insert:      []pair{{1, 1}, {2, 2}, {3, 3}, {4, 4}},
+			lo:          f(2),
+			hi:          f(3),
+			loInclusive: true,
+			hiInclusive: true,
+			want:        []pair{{2, 2}, {3, 3}},
+			wantLen:     4,
+		},
+		{
+			name:    "Deleted pairs",
+			insert:  []pair{{1, 1}, {2, 2}, {2, 3}},
+			delete:  []pair{{2, 2}, {5, 5}},
+			want:    []pair{{1, 1}, {2, 3}},
+			wantLen: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newSkipList[float64]()
+			for _, p := range tt.insert {
+				l.Insert(p.Key, p.N)
+			}
+			for _, p := range tt.delete {
+				l.Delete(p.Key, p.N)
+			}
+
+			var got []pair
+			l.Ascend(tt.lo, tt.hi, tt.loInclusive, tt.hiInclusive, func(key float64, n uint32) bool {
+				got = append(got, pair{key, n})
+				return true
+			})
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("skipList.Ascend() mismatch (-want +got):\n%s", diff)
+			}
+			if l.Len() != tt.wantLen {
+				t.Errorf("skipList.Len() = %v, want %v", l.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestSkipList_large(t *testing.T) {
+	l := newSkipList[float64]()
+	for i := 0; i < 1000; i++ {
+		l.Insert(float64((i*7919)%1000), uint32(i))
+	}
+	for i := 0; i < 1000; i += 2 {
+		l.Delete(float64((i*7919)%1000), uint32(i))
+	}
+
+	prev := -1.0
+	count := 0
+	l.Ascend(nil, nil, false, false, func(key float64, n uint32) bool {
+		if key <= prev {
+			t.Fatalf("skipList is not ordered: %v after %v", key, prev)
+		}
+		prev = key
+		count++
+		return true
+	})
+	if count != 500 {
+		t.Errorf("skipList has %d pairs, want 500", count)
+	}
+}
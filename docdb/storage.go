@@ -0,0 +1,130 @@
+package docdb
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/patrickmn/go-cache"
+)
+
+type Store interface {
+	Get(id string) ([]byte, error)
+	Put(id string, doc []byte) error
+	Delete(id string, rev uint64) error
+	Iterate(fn func(id string, doc []byte) bool) error
+	Batch(ops []BatchOp) error
+	Close() error
+
+	// SaveIndexes and LoadIndexes keep the index definitions along with the
+	// documents, so that they survive a restart.
+	SaveIndexes(defs []IndexDefinition) error
+	LoadIndexes() ([]IndexDefinition, error)
+
+	// Revision returns the highest revision given to Delete, so that the
+	// revisions of deleted documents are not reused after a restart.
+	Revision() uint64
+}
+
+type BatchOp struct {
+	ID     string
+	Doc    []byte
+	Delete bool
+	// Rev is the revision of the deleted document.
+	Rev uint64
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	db      *cache.Cache
+	indexes []IndexDefinition
+	rev     uint64
+}
+
+func (s *MemoryStore) Get(id string) ([]byte, error) {
+	item, ok := s.db.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	b, ok := item.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected data in %s", id)
+	}
+	return b, nil
+}
+
+func (s *MemoryStore) Put(id string, doc []byte) error {
+	s.db.Set(id, doc, cache.NoExpiration)
+	return nil
+}
+
+func (s *MemoryStore) Delete(id string, rev uint64) error {
+	s.db.Delete(id)
+	s.keepRevision(rev)
+	return nil
+}
+
+func (s *MemoryStore) Iterate(fn func(id string, doc []byte) bool) error {
+	for id, item := range s.db.Items() {
+		b, ok := item.Object.([]byte)
+		if !ok {
+			return fmt.Errorf("unexpected data in %s", id)
+		}
+		if !fn(id, b) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Batch(ops []BatchOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, op := range ops {
+		if op.Delete {
+			s.db.Delete(op.ID)
+			if op.Rev > s.rev {
+				s.rev = op.Rev
+			}
+			continue
+		}
+		s.db.Set(op.ID, op.Doc, cache.NoExpiration)
+	}
+	return nil
+}
+
+func (s *MemoryStore) SaveIndexes(defs []IndexDefinition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indexes = append([]IndexDefinition(nil), defs...)
+	return nil
+}
+
+func (s *MemoryStore) LoadIndexes() ([]IndexDefinition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]IndexDefinition(nil), s.indexes...), nil
+}
+
+func (s *MemoryStore) Revision() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rev
+}
+
+func (s *MemoryStore) keepRevision(rev uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rev > s.rev {
+		s.rev = rev
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		db: cache.New(cache.NoExpiration, 0),
+	}
+}
@@ -0,0 +1,148 @@
+package docdb
+
+import (
+	"log"
+	"math"
+
+	"github.com/x-color/docdb-in-go/query"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type textDoc struct {
+	length int
+	tf     map[string]int
+}
+
+type textPath struct {
+	docs   map[string]textDoc
+	df     map[string]int
+	length int
+}
+
+// textIndex keeps the statistics of full-text indexed paths which BM25 needs.
+// The postings of the words are kept in Index. It is guarded by DocDB.mu.
+type textIndex struct {
+	paths map[string]*textPath
+}
+
+func (t *textIndex) set(id string, tokens map[string][]string) {
+	for path, tp := range t.paths {
+		doc, ok := tp.docs[id]
+		if !ok {
+			continue
+		}
+		for token := range doc.tf {
+			tp.df[token]--
+			if tp.df[token] == 0 {
+				delete(tp.df, token)
+			}
+		}
+		tp.length -= doc.length
+		delete(tp.docs, id)
+		if len(tp.docs) == 0 {
+			delete(t.paths, path)
+		}
+	}
+
+	for path, ts := range tokens {
+		tp, ok := t.paths[path]
+		if !ok {
+			tp = &textPath{docs: map[string]textDoc{}, df: map[string]int{}}
+			t.paths[path] = tp
+		}
+		doc := textDoc{length: len(ts), tf: map[string]int{}}
+		for _, token := range ts {
+			doc.tf[token]++
+		}
+		for token := range doc.tf {
+			tp.df[token]++
+		}
+		tp.length += doc.length
+		tp.docs[id] = doc
+	}
+}
+
+// score is the BM25 score of the document for the words at the path.
+func (t *textIndex) score(path, id string, words []string) float64 {
+	tp, ok := t.paths[path]
+	if !ok {
+		return 0
+	}
+	doc, ok := tp.docs[id]
+	if !ok {
+		return 0
+	}
+
+	n := float64(len(tp.docs))
+	avg := float64(tp.length) / n
+	var score float64
+	for _, w := range words {
+		tf := float64(doc.tf[w])
+		if tf == 0 {
+			continue
+		}
+		df := float64(tp.df[w])
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := 1 - bm25B
+		if avg > 0 {
+			norm += bm25B * float64(doc.length) / avg
+		}
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return score
+}
+
+func newTextIndex() *textIndex {
+	return &textIndex{paths: map[string]*textPath{}}
+}
+
+// textTokens returns the words of the strings at full-text indexed paths.
+func (d DocDB) textTokens(doc map[string]any) map[string][]string {
+	tokens := make(map[string][]string)
+	leaves(doc, "", func(path string, v any) {
+		s, ok := v.(string)
+		if !ok || d.indexes.lookup(path).Kind != IndexText {
+			return
+		}
+		tokens[path] = append(tokens[path], query.Tokenize(s)...)
+	})
+	return tokens
+}
+
+func addTextKeys(keys map[indexKey]bool, path string, v any) {
+	s, ok := v.(string)
+	if !ok {
+		return
+	}
+	for _, token := range query.Tokenize(s) {
+		keys[indexKey{term: path + "~" + token}] = true
+	}
+}
+
+// textPostings returns the documents which have every word of the value at
+// the path.
+func (d DocDB) textPostings(path, value string) (Postings, error) {
+	words := query.Tokenize(value)
+	if len(words) == 0 {
+		return Postings{}, nil
+	}
+
+	var matched Postings
+	for i, w := range words {
+		p, err := d.index.Lookup(path + "~" + w)
+		if err != nil {
+			log.Printf("failed to get data from index: %s~%s: %s", path, w, err)
+			return Postings{}, ErrFatal
+		}
+		if i == 0 {
+			matched = p
+			continue
+		}
+		matched = matched.And(p)
+	}
+	return matched, nil
+}
@@ -0,0 +1,111 @@
+package docdb
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/x-color/docdb-in-go/query"
+)
+
+func TestDocDB_Search_text(t *testing.T) {
+	docs := map[string]map[string]any{
+		"a": {"description": "A sample book about databases"},
+		"b": {"description": "Book of books: a book which lists sample books"},
+		"c": {"description": "A sample of music"},
+		"d": {"description": "Sample book"},
+		"e": {"title": "sample book"},
+	}
+	tests := []struct {
+		name    string
+		defs    []IndexDefinition
+		q       string
+		wantIDs []string
+	}{
+		{
+			name:    "Rank by BM25",
+			defs:    []IndexDefinition{{Path: "description", Kind: IndexText}},
+			q:       `description:~"sample book"`,
+			wantIDs: []string{"d", "b", "a"},
+		},
+		{
+			name:    "Single word",
+			defs:    []IndexDefinition{{Path: "description", Kind: IndexText}},
+			q:       `description:~"databases"`,
+			wantIDs: []string{"a"},
+		},
+		{
+			name:    "Unindexed path is scanned",
+			q:       `description:~"sample book"`,
+			wantIDs: []string{"a", "b", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(WithIndexes(tt.defs...))
+			defer d.Close()
+			for id, doc := range docs {
+				if _, _, err := d.Upsert(id, doc); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			qs, err := query.ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := d.Search(qs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0)
+			for _, r := range res {
+				ids = append(ids, r["id"].(string))
+				if _, ok := r["score"].(float64); !ok {
+					t.Errorf("DocDB.Search() hit %v has no score", r["id"])
+				}
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("DocDB.Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_textIndex(t *testing.T) {
+	d := NewDocDB(WithIndexes(IndexDefinition{Path: "description", Kind: IndexText}))
+	defer d.Close()
+	if _, _, err := d.Upsert("a", map[string]any{"description": "sample book"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Update("a", map[string]any{"description": "sample music"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{term: "description~book", want: nil},
+		{term: "description~music", want: []string{"a"}},
+		{term: `description="sample music"`, want: nil},
+		{term: "description", want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			ids, err := d.lookup(tt.term)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, ids); diff != "" {
+				t.Errorf("DocDB.lookup() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if err := d.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.texts.paths) != 0 {
+		t.Errorf("statistics of deleted document are left: %v", d.texts.paths)
+	}
+}
@@ -0,0 +1,243 @@
+package docdb
+
+import (
+	"errors"
+	"log"
+	"sort"
+)
+
+var (
+	ErrTxDone     = errors.New("transaction done error")
+	ErrTxConflict = errors.New("transaction conflict error")
+)
+
+// Tx is a transaction over documents. Its writes are kept in the transaction
+// and are not visible to others, including through the index, until Commit
+// applies them at once. Commit fails with ErrTxConflict if a document which
+// the transaction read or wrote has been changed by others since, so that
+// committed transactions are serializable. A Tx must not be used concurrently.
+type Tx struct {
+	db     DocDB
+	reads  map[string]uint64
+	writes map[string]map[string]any
+	order  []string
+	done   bool
+}
+
+func (d DocDB) Begin() *Tx {
+	return &Tx{
+		db:     d,
+		reads:  map[string]uint64{},
+		writes: map[string]map[string]any{},
+	}
+}
+
+// Get returns the document as the transaction sees it, along with the
+// revision it had when the transaction first read it.
+func (tx *Tx) Get(id string) (map[string]any, uint64, error) {
+	doc, rev, err := tx.current(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if doc == nil {
+		return nil, 0, ErrNotFound
+	}
+	return doc, rev, nil
+}
+
+func (tx *Tx) Add(doc map[string]any) (string, error) {
+	id, err := tx.db.docID(doc)
+	if err != nil {
+		return "", err
+	}
+	old, _, err := tx.current(id)
+	if err != nil {
+		return "", err
+	}
+	if old != nil {
+		return "", ErrAlreadyExists
+	}
+
+	tx.write(id, doc)
+	return id, nil
+}
+
+func (tx *Tx) Upsert(id string, doc map[string]any, conds ...Condition) error {
+	if err := tx.db.checkID(id, doc); err != nil {
+		return err
+	}
+	old, rev, err := tx.current(id)
+	if err != nil {
+		return err
+	}
+	if err := checkConditions(conds, rev, old != nil); err != nil {
+		return err
+	}
+
+	tx.write(id, doc)
+	return nil
+}
+
+func (tx *Tx) Update(id string, doc map[string]any, conds ...Condition) error {
+	if err := tx.db.checkID(id, doc); err != nil {
+		return err
+	}
+	old, rev, err := tx.current(id)
+	if err != nil {
+		return err
+	}
+	if err := checkConditions(conds, rev, old != nil); err != nil {
+		return err
+	}
+	if old == nil {
+		return ErrNotFound
+	}
+
+	tx.write(id, doc)
+	return nil
+}
+
+func (tx *Tx) Delete(id string, conds ...Condition) error {
+	old, rev, err := tx.current(id)
+	if err != nil {
+		return err
+	}
+	if err := checkConditions(conds, rev, old != nil); err != nil {
+		return err
+	}
+	if old == nil {
+		return ErrNotFound
+	}
+
+	tx.write(id, nil)
+	return nil
+}
+
+// current returns the document as the transaction sees it. The revision a
+// document had when it was first read is kept, so that Commit can tell whether
+// it has been changed since.
+func (tx *Tx) current(id string) (map[string]any, uint64, error) {
+	if tx.done {
+		return nil, 0, ErrTxDone
+	}
+	if doc, ok := tx.writes[id]; ok {
+		return doc, tx.reads[id], nil
+	}
+
+	doc, rev, err := tx.db.GetWithRevision(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, 0, err
+	}
+	if read, ok := tx.reads[id]; ok && read != rev {
+		return nil, 0, ErrTxConflict
+	}
+	tx.reads[id] = rev
+	return doc, rev, nil
+}
+
+func (tx *Tx) write(id string, doc map[string]any) {
+	if _, ok := tx.writes[id]; !ok {
+		tx.order = append(tx.order, id)
+	}
+	tx.writes[id] = doc
+}
+
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	return nil
+}
+
+// Commit applies the writes of the transaction at once, and returns the new
+// revisions of the written documents. Nothing is applied when it fails.
+func (tx *Tx) Commit() (map[string]uint64, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	tx.done = true
+
+	d := tx.db
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := make([]string, 0, len(tx.reads))
+	for id := range tx.reads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	prevs := make(map[string]record)
+	olds := make(map[string]map[string]any)
+	for _, id := range ids {
+		prev, old, err := d.loadCurrent(id)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if prev.Rev != tx.reads[id] {
+			return nil, ErrTxConflict
+		}
+		prevs[id], olds[id] = prev, old
+	}
+
+	changes := make([]change, 0, len(tx.order))
+	rev := *d.rev
+	for _, id := range tx.order {
+		doc := tx.writes[id]
+		if doc == nil {
+			if olds[id] != nil {
+				changes = append(changes, change{id: id, rev: prevs[id].Rev, old: olds[id]})
+			}
+			continue
+		}
+		rev++
+		c, err := d.prepare(id, prevs[id], olds[id], doc, rev)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	// Old unique values of every change are released first, so that changes
+	// may swap values. New values are set as each change is checked, so that
+	// changes of the transaction are checked against each other.
+	for _, c := range changes {
+		d.uniques.set(c.id, c.old, nil)
+	}
+	restore := func(set int) {
+		for _, c := range changes[:set] {
+			d.uniques.set(c.id, c.doc, nil)
+		}
+		for _, c := range changes {
+			d.uniques.set(c.id, nil, c.old)
+		}
+	}
+	for i, c := range changes {
+		if err := d.checkUnique(c.id, c.doc); err != nil {
+			restore(i)
+			return nil, err
+		}
+		d.uniques.set(c.id, nil, c.doc)
+	}
+
+	ops := make([]BatchOp, 0, len(changes))
+	for _, c := range changes {
+		ops = append(ops, BatchOp{ID: c.id, Doc: c.data, Delete: c.doc == nil, Rev: c.rev})
+	}
+	if err := d.store.Batch(ops); err != nil {
+		log.Printf("failed to store documents: %s\n", err)
+		restore(len(changes))
+		return nil, ErrFatal
+	}
+
+	*d.rev = rev
+	revs := make(map[string]uint64)
+	for _, c := range changes {
+		d.apply(c)
+		if c.doc != nil {
+			revs[c.id] = c.rev
+		}
+	}
+	return revs, nil
+}
@@ -0,0 +1,284 @@
+package docdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/x-color/docdb-in-go/query"
+)
+
+func TestTx(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(d *DocDB, tx *Tx) error
+		wantErr  error
+		wantDocs map[string]map[string]any
+	}{
+		{
+			name: "Commit writes",
+			run: func(d *DocDB, tx *Tx) error {
+				if err := tx.Upsert("order", map[string]any{"item": "stock", "qty": 2}); err != nil {
+					return err
+				}
+				if err := tx.Update("stock", map[string]any{"qty": 8}); err != nil {
+					return err
+				}
+				return tx.Delete("old")
+			},
+			wantDocs: map[string]map[string]any{
+				"stock": {"qty": float64(8)},
+				"order": {"item": "stock", "qty": float64(2)},
+			},
+		},
+		{
+			name: "Read own writes",
+			run: func(d *DocDB, tx *Tx) error {
+				if err := tx.Update("stock", map[string]any{"qty": 8}); err != nil {
+					return err
+				}
+				doc, _, err := tx.Get("stock")
+				if err != nil {
+					return err
+				}
+				if doc["qty"] != 8 {
+					return fmt.Errorf("Tx.Get() = %v, want own write", doc)
+				}
+				if err := tx.Delete("old"); err != nil {
+					return err
+				}
+				if _, _, err := tx.Get("old"); !errors.Is(err, ErrNotFound) {
+					return fmt.Errorf("Tx.Get() error = %v, want ErrNotFound", err)
+				}
+				return nil
+			},
+			wantDocs: map[string]map[string]any{
+				"stock": {"qty": float64(8)},
+			},
+		},
+		{
+			name: "Writes are not visible before commit",
+			run: func(d *DocDB, tx *Tx) error {
+				if err := tx.Update("stock", map[string]any{"qty": 8}); err != nil {
+					return err
+				}
+				doc, err := d.Get("stock")
+				if err != nil {
+					return err
+				}
+				if doc["qty"] != float64(10) {
+					return fmt.Errorf("DocDB.Get() = %v before commit", doc)
+				}
+				qs, err := query.ParseQuery("qty:8")
+				if err != nil {
+					return err
+				}
+				if ids, _ := d.candidates(qs); len(ids) != 0 {
+					return fmt.Errorf("index has %v before commit", ids)
+				}
+				return nil
+			},
+			wantDocs: map[string]map[string]any{
+				"stock": {"qty": float64(8)},
+			},
+		},
+		{
+			name: "Conflict with write of others",
+			run: func(d *DocDB, tx *Tx) error {
+				if _, _, err := tx.Get("stock"); err != nil {
+					return err
+				}
+				if err := tx.Upsert("order", map[string]any{"qty": 2}); err != nil {
+					return err
+				}
+				_, err := d.Update("stock", map[string]any{"qty": 9})
+				return err
+			},
+			wantErr: ErrTxConflict,
+			wantDocs: map[string]map[string]any{
+				"stock": {"qty": float64(9)},
+			},
+		},
+		{
+			name: "Conflict with creation by others",
+			run: func(d *DocDB, tx *Tx) error {
+				if err := tx.Upsert("order", map[string]any{"qty": 2}); err != nil {
+					return err
+				}
+				_, _, err := d.Upsert("order", map[string]any{"qty": 3})
+				return err
+			},
+			wantErr: ErrTxConflict,
+			wantDocs: map[string]map[string]any{
+				"order": {"qty": float64(3)},
+			},
+		},
+		{
+			name: "Failed precondition",
+			run: func(d *DocDB, tx *Tx) error {
+				return tx.Update("stock", map[string]any{"qty": 8}, IfMatch(100))
+			},
+			wantErr: ErrConflict,
+		},
+		{
+			name: "Unique values are checked within transaction",
+			run: func(d *DocDB, tx *Tx) error {
+				if err := tx.Upsert("a", map[string]any{"email": "a@example.com"}); err != nil {
+					return err
+				}
+				return tx.Upsert("b", map[string]any{"email": "a@example.com"})
+			},
+			wantErr: ErrConflict,
+		},
+		{
+			name: "Unique values are swapped within transaction",
+			run: func(d *DocDB, tx *Tx) error {
+				if _, _, err := d.Upsert("other", map[string]any{"email": "other@example.com"}); err != nil {
+					return err
+				}
+				if err := tx.Update("stock", map[string]any{"email": "other@example.com"}); err != nil {
+					return err
+				}
+				return tx.Update("other", map[string]any{"email": "stock@example.com"})
+			},
+			wantDocs: map[string]map[string]any{
+				"stock": {"email": "other@example.com"},
+				"other": {"email": "stock@example.com"},
+			},
+		},
+		{
+			name: "Failed unique check keeps values",
+			run: func(d *DocDB, tx *Tx) error {
+				if _, _, err := d.Upsert("other", map[string]any{"email": "other@example.com"}); err != nil {
+					return err
+				}
+				if err := tx.Update("stock", map[string]any{"email": "new@example.com"}); err != nil {
+					return err
+				}
+				if err := tx.Upsert("order", map[string]any{"email": "other@example.com"}); err != nil {
+					return err
+				}
+				if _, err := tx.Commit(); !errors.Is(err, ErrConflict) {
+					return fmt.Errorf("Tx.Commit() error = %v, want ErrConflict", err)
+				}
+				_, err := d.Add(map[string]any{"email": "stock@example.com"})
+				return err
+			},
+			wantErr: ErrConflict,
+		},
+		{
+			name: "Unique value is freed within transaction",
+			run: func(d *DocDB, tx *Tx) error {
+				if err := tx.Update("stock", map[string]any{"qty": 10}); err != nil {
+					return err
+				}
+				return tx.Upsert("stock2", map[string]any{"qty": 10, "email": "stock@example.com"})
+			},
+			wantDocs: map[string]map[string]any{
+				"stock":  {"qty": float64(10)},
+				"stock2": {"qty": float64(10), "email": "stock@example.com"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(WithIndexes(IndexDefinition{Path: "email", Kind: IndexUnique}))
+			defer d.Close()
+			if _, _, err := d.Upsert("stock", map[string]any{"qty": 10, "email": "stock@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := d.Upsert("old", map[string]any{"qty": 0}); err != nil {
+				t.Fatal(err)
+			}
+
+			tx := d.Begin()
+			err := tt.run(d, tx)
+			if err == nil {
+				_, err = tx.Commit()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Tx error = %v, want %v", err, tt.wantErr)
+			}
+
+			for id, want := range tt.wantDocs {
+				got, err := d.Get(id)
+				if err != nil {
+					t.Fatalf("DocDB.Get(%s) error = %v", id, err)
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("DocDB.Get(%s) mismatch (-want +got):\n%s", id, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestTx_Rollback(t *testing.T) {
+	d := NewDocDB()
+	defer d.Close()
+
+	tx := d.Begin()
+	if _, err := tx.Add(map[string]any{"name": "bookA"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Tx.Commit() error = %v, want ErrTxDone", err)
+	}
+	if _, err := tx.Add(map[string]any{"name": "bookB"}); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Tx.Add() error = %v, want ErrTxDone", err)
+	}
+	if stats, _ := d.Stats(); stats.Documents != 0 {
+		t.Errorf("%d documents after rollback, want 0", stats.Documents)
+	}
+}
+
+func TestTx_concurrent(t *testing.T) {
+	d := NewDocDB()
+	defer d.Close()
+	if _, _, err := d.Upsert("counter", map[string]any{"n": 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				tx := d.Begin()
+				doc, _, err := tx.Get("counter")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// A conflict is also found early when the document has been
+				// changed since it was read.
+				err = tx.Update("counter", map[string]any{"n": doc["n"].(float64) + 1})
+				if err == nil {
+					_, err = tx.Commit()
+				}
+				if err == nil {
+					return
+				}
+				if !errors.Is(err, ErrTxConflict) {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	doc, err := d.Get("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["n"] != float64(20) {
+		t.Errorf("counter = %v, want 20", doc["n"])
+	}
+}
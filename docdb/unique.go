@@ -0,0 +1,147 @@
+package docdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// UniqueError is returned when a write would give a unique field a value
+// which another document already has.
+type UniqueError struct {
+	Field string
+	Value any
+	ID    string
+}
+
+func (e *UniqueError) Error() string {
+	return fmt.Sprintf("%s %v is already used by %s", e.Field, e.Value, e.ID)
+}
+
+func (e *UniqueError) Unwrap() error {
+	return ErrConflict
+}
+
+// uniqueIndex maps the values of unique fields to the documents which have
+// them. It is kept apart from Index so that the constraint holds while the
+// index of the field is built. It is guarded by DocDB.mu.
+type uniqueIndex struct {
+	values map[string]map[string]string
+}
+
+// build starts enforcing uniqueness of the path over docs.
+func (u *uniqueIndex) build(path string, docs map[string]map[string]any) error {
+	ids := make([]string, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	m := make(map[string]string)
+	for _, id := range ids {
+		for key, v := range uniqueValues(docs[id], path) {
+			if other, ok := m[key]; ok && other != id {
+				return &UniqueError{Field: path, Value: v, ID: other}
+			}
+			m[key] = id
+		}
+	}
+	u.values[path] = m
+	return nil
+}
+
+func (u *uniqueIndex) drop(path string) {
+	delete(u.values, path)
+}
+
+func (u *uniqueIndex) check(id string, doc map[string]any) error {
+	paths := make([]string, 0, len(u.values))
+	for path := range u.values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for key, v := range uniqueValues(doc, path) {
+			if other, ok := u.values[path][key]; ok && other != id {
+				return &UniqueError{Field: path, Value: v, ID: other}
+			}
+		}
+	}
+	return nil
+}
+
+func (u *uniqueIndex) set(id string, old, doc map[string]any) {
+	for path, m := range u.values {
+		for key := range uniqueValues(old, path) {
+			if m[key] == id {
+				delete(m, key)
+			}
+		}
+		for key := range uniqueValues(doc, path) {
+			m[key] = id
+		}
+	}
+}
+
+// uniqueValues returns the values at the path keyed by their JSON encoding,
+// so that 1 and "1" are different values.
+func uniqueValues(doc map[string]any, path string) map[string]any {
+	values := make(map[string]any)
+	leaves(doc, "", func(p string, v any) {
+		// Missing and null values do not take part in the constraint.
+		if p != path || v == nil {
+			return
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			log.Printf("failed to encode unique value: %s: %s", path, err)
+			return
+		}
+		values[string(b)] = v
+	})
+	return values
+}
+
+// checkUnique checks doc against the unique constraints. A document which
+// holds the value but has expired is removed, so that it does not block doc.
+func (d DocDB) checkUnique(id string, doc map[string]any) error {
+	for {
+		err := d.uniques.check(id, doc)
+		var ue *UniqueError
+		if !errors.As(err, &ue) {
+			return err
+		}
+		r, _, lerr := d.load(ue.ID)
+		if lerr != nil || !r.expired(d.now()) {
+			return err
+		}
+		if _, _, lerr := d.loadCurrent(ue.ID); !errors.Is(lerr, ErrNotFound) {
+			return err
+		}
+	}
+}
+
+// liveDocuments returns every document which has not expired.
+func (d DocDB) liveDocuments() (map[string]map[string]any, error) {
+	docs := make(map[string]map[string]any)
+	now := d.now()
+	err := d.store.Iterate(func(id string, b []byte) bool {
+		r, doc, err := decodeRecord(b)
+		if err != nil {
+			log.Printf("failed to convert data to document: %s: %s", id, err)
+			return true
+		}
+		if !r.expired(now) {
+			docs[id] = doc
+		}
+		return true
+	})
+	return docs, err
+}
+
+func newUniqueIndex() *uniqueIndex {
+	return &uniqueIndex{values: map[string]map[string]string{}}
+}
@@ -0,0 +1,260 @@
+package docdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDocDB_uniqueIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   func(d *DocDB) error
+		wantErr *UniqueError
+	}{
+		{
+			name: "Add duplicated value",
+			write: func(d *DocDB) error {
+				_, err := d.Add(map[string]any{"email": "a@example.com"})
+				return err
+			},
+			wantErr: &UniqueError{Field: "email", Value: "a@example.com", ID: "a"},
+		},
+		{
+			name: "Add unique value",
+			write: func(d *DocDB) error {
+				_, err := d.Add(map[string]any{"email": "c@example.com"})
+				return err
+			},
+		},
+		{
+			name: "Value of different type",
+			write: func(d *DocDB) error {
+				_, err := d.Add(map[string]any{"sku": "1"})
+				return err
+			},
+		},
+		{
+			name: "Update to duplicated value",
+			write: func(d *DocDB) error {
+				_, err := d.Update("b", map[string]any{"email": "a@example.com"})
+				return err
+			},
+			wantErr: &UniqueError{Field: "email", Value: "a@example.com", ID: "a"},
+		},
+		{
+			name: "Update keeping own value",
+			write: func(d *DocDB) error {
+				_, err := d.Update("a", map[string]any{"email": "a@example.com", "name": "alice"})
+				return err
+			},
+		},
+		{
+			name: "Patch to duplicated value",
+			write: func(d *DocDB) error {
+				_, err := d.MergePatch("b", map[string]any{"sku": 1})
+				return err
+			},
+			wantErr: &UniqueError{Field: "sku", Value: 1, ID: "a"},
+		},
+		{
+			name: "Duplicated value in array",
+			write: func(d *DocDB) error {
+				_, _, err := d.Upsert("c", map[string]any{"email": []any{"c@example.com", "b@example.com"}})
+				return err
+			},
+			wantErr: &UniqueError{Field: "email", Value: "b@example.com", ID: "b"},
+		},
+		{
+			name: "Value of deleted document",
+			write: func(d *DocDB) error {
+				if err := d.Delete("a"); err != nil {
+					return err
+				}
+				_, err := d.Add(map[string]any{"email": "a@example.com"})
+				return err
+			},
+		},
+		{
+			name: "Null values",
+			write: func(d *DocDB) error {
+				if _, err := d.Add(map[string]any{"email": nil}); err != nil {
+					return err
+				}
+				_, err := d.Add(map[string]any{"email": nil})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDocDB(WithIndexes(
+				IndexDefinition{Path: "email", Kind: IndexUnique},
+				IndexDefinition{Path: "sku", Kind: IndexUnique},
+			))
+			defer d.Close()
+			if _, _, err := d.Upsert("a", map[string]any{"email": "a@example.com", "sku": 1}); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := d.Upsert("b", map[string]any{"email": "b@example.com", "sku": 2}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := tt.write(d)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrConflict) {
+				t.Errorf("error = %v, want ErrConflict", err)
+			}
+			var ue *UniqueError
+			if !errors.As(err, &ue) {
+				t.Fatalf("error = %v, want UniqueError", err)
+			}
+			if diff := cmp.Diff(tt.wantErr, ue); diff != "" {
+				t.Errorf("UniqueError mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocDB_CreateIndex_unique(t *testing.T) {
+	d := NewDocDB()
+	defer d.Close()
+	for id, email := range map[string]string{"a": "a@example.com", "b": "a@example.com"} {
+		if _, _, err := d.Upsert(id, map[string]any{"email": email}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ue *UniqueError
+	if err := d.CreateIndex("email", IndexUnique); !errors.As(err, &ue) {
+		t.Fatalf("DocDB.CreateIndex() error = %v, want UniqueError", err)
+	}
+	if diff := cmp.Diff(&UniqueError{Field: "email", Value: "a@example.com", ID: "a"}, ue); diff != "" {
+		t.Errorf("UniqueError mismatch (-want +got):\n%s", diff)
+	}
+	if len(d.Indexes()) != 0 {
+		t.Errorf("DocDB.Indexes() = %v, want no index", d.Indexes())
+	}
+
+	if _, err := d.Update("b", map[string]any{"email": "b@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateIndex("email", IndexUnique); err != nil {
+		t.Fatalf("DocDB.CreateIndex() error = %v", err)
+	}
+	if _, err := d.Add(map[string]any{"email": "b@example.com"}); !errors.As(err, &ue) {
+		t.Errorf("DocDB.Add() error = %v, want UniqueError", err)
+	}
+
+	if err := d.CreateIndex("email", IndexEquality); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Add(map[string]any{"email": "b@example.com"}); err != nil {
+		t.Errorf("DocDB.Add() error = %v after unique index is replaced", err)
+	}
+}
+
+func TestDocDB_uniqueIndex_expired(t *testing.T) {
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDocDB(WithExpiryInterval(0), WithIndexes(IndexDefinition{Path: "email", Kind: IndexUnique}))
+	defer d.Close()
+	now := base
+	d.now = func() time.Time { return now }
+
+	if _, _, err := d.Upsert("a", map[string]any{"email": "a@example.com", "_expiresAt": "2022-01-01T01:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	now = base.Add(2 * time.Hour)
+	if _, err := d.Add(map[string]any{"email": "a@example.com"}); err != nil {
+		t.Errorf("DocDB.Add() error = %v, want expired document not to block", err)
+	}
+}
+
+func TestDocDB_uniqueIndex_concurrent(t *testing.T) {
+	d := NewDocDB(WithIndexes(IndexDefinition{Path: "email", Kind: IndexUnique}))
+	defer d.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := d.Upsert(fmt.Sprint(i), map[string]any{"email": "a@example.com"})
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrConflict) {
+				t.Errorf("DocDB.Upsert() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("%d documents are created with the same email, want 1", created)
+	}
+}
+
+func TestDocDB_uniqueIndex_restart(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDocDB(WithStore(s))
+	if err := d.CreateIndex("sku", IndexUnique); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Add(map[string]any{"sku": "B-12"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewFileStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err = Open(WithStore(s))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer d.Close()
+
+	want := []IndexDefinition{{Path: "sku", Kind: IndexUnique, Ready: true}}
+	if diff := cmp.Diff(want, d.Indexes()); diff != "" {
+		t.Errorf("DocDB.Indexes() mismatch (-want +got):\n%s", diff)
+	}
+	var ue *UniqueError
+	if _, err := d.Add(map[string]any{"sku": "B-12"}); !errors.As(err, &ue) {
+		t.Errorf("DocDB.Add() error = %v, want UniqueError after restart", err)
+	}
+}
+
+func TestOpen_duplicatedUniqueValues(t *testing.T) {
+	s := NewMemoryStore()
+	d := NewDocDB(WithStore(s))
+	for _, id := range []string{"a", "b"} {
+		if _, _, err := d.Upsert(id, map[string]any{"sku": "B-12"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ue *UniqueError
+	if _, err := Open(WithStore(s), WithIndexes(IndexDefinition{Path: "sku", Kind: IndexUnique})); !errors.As(err, &ue) {
+		t.Errorf("Open() error = %v, want UniqueError", err)
+	}
+}
@@ -0,0 +1,209 @@
+package docdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.db"
+)
+
+type walOp string
+
+const (
+	walOpPut    walOp = "put"
+	walOpDelete walOp = "delete"
+	walOpBatch  walOp = "batch"
+	// walOpRev keeps the revision of deleted documents in a snapshot.
+	walOpRev walOp = "rev"
+)
+
+type walRecord struct {
+	Op  walOp           `json:"op"`
+	ID  string          `json:"id,omitempty"`
+	Doc json.RawMessage `json:"doc,omitempty"`
+	Ops []walRecord     `json:"ops,omitempty"`
+	Rev uint64          `json:"rev,omitempty"`
+}
+
+// walFile is the file of the log. It is an *os.File except in tests.
+type walFile interface {
+	io.Writer
+	io.Seeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+	Stat() (os.FileInfo, error)
+}
+
+type wal struct {
+	mu   sync.Mutex
+	dir  string
+	file walFile
+}
+
+func (w *wal) write(r walRecord, apply func()) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	info, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := w.file.Write(append(b, '\n')); err != nil {
+		return w.rollback(info.Size(), err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return w.rollback(info.Size(), err)
+	}
+
+	apply()
+	return nil
+}
+
+// rollback drops the bytes of a failed write, so that a later record is not
+// appended behind a torn one, and a record which failed to be synced is not
+// replayed.
+func (w *wal) rollback(size int64, err error) error {
+	if terr := w.file.Truncate(size); terr != nil {
+		return fmt.Errorf("%w, and failed to truncate wal: %v", err, terr)
+	}
+	return err
+}
+
+func (w *wal) replay(apply func(walRecord) error) error {
+	if _, err := readRecords(filepath.Join(w.dir, snapshotFileName), false, apply); err != nil {
+		return fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	size, err := readRecords(filepath.Join(w.dir, walFileName), true, apply)
+	if err != nil {
+		return fmt.Errorf("failed to replay wal: %w", err)
+	}
+
+	// Drop a torn record left by a crash so that new records are not
+	// appended behind it.
+	return w.file.Truncate(size)
+}
+
+func (w *wal) snapshot(records func() []walRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tmp := filepath.Join(w.dir, snapshotFileName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, r := range records() {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(w.dir, snapshotFileName)); err != nil {
+		return err
+	}
+	if err := syncDir(w.dir); err != nil {
+		return err
+	}
+
+	// Every record in the log is now covered by the snapshot.
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+func readRecords(path string, allowTornTail bool, apply func(walRecord) error) (int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var size int64
+	br := bufio.NewReader(f)
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A record without a trailing newline was never synced
+			// completely, so it is not part of the log.
+			if len(line) != 0 && !allowTornTail {
+				return 0, fmt.Errorf("unexpected end of %s", path)
+			}
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		r := walRecord{}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return 0, fmt.Errorf("broken record in %s: %w", path, err)
+		}
+		if err := apply(r); err != nil {
+			return 0, err
+		}
+		size += int64(len(line))
+	}
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func openWAL(dir string) (*wal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &wal{
+		dir:  dir,
+		file: f,
+	}, nil
+}
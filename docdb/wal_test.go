@@ -0,0 +1,156 @@
+package docdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWAL_replay(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name:    "Replay all records",
+			log:     "{\"op\":\"put\",\"id\":\"a\",\"doc\":{}}\n{\"op\":\"put\",\"id\":\"b\",\"doc\":{}}\n",
+			wantIDs: []string{"a", "b"},
+		},
+		{
+			name:    "Ignore torn record",
+			log:     "{\"op\":\"put\",\"id\":\"a\",\"doc\":{}}\n{\"op\":\"put\",\"id\":\"b\"",
+			wantIDs: []string{"a"},
+		},
+		{
+			name:    "Broken record",
+			log:     "{\"op\":\"put\",\"id\":\"a\",\"doc\":{}}\nbroken\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, walFileName), []byte(tt.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			w, err := openWAL(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.close()
+
+			var ids []string
+			err = w.replay(func(r walRecord) error {
+				ids = append(ids, r.ID)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wal.replay() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("wal.replay() mismatch (-want +got):\n%s", diff)
+			}
+
+			if err := w.write(walRecord{Op: walOpPut, ID: "c"}, func() {}); err != nil {
+				t.Fatal(err)
+			}
+			ids = nil
+			if err := w.replay(func(r walRecord) error {
+				ids = append(ids, r.ID)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(append(tt.wantIDs, "c"), ids); diff != "" {
+				t.Errorf("wal.replay() after write mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// failingFile writes only the first half of a record, or fails to sync it.
+type failingFile struct {
+	*os.File
+	tornWrite bool
+	failSync  bool
+}
+
+func (f *failingFile) Write(b []byte) (int, error) {
+	if f.tornWrite {
+		n, _ := f.File.Write(b[:len(b)/2])
+		return n, errors.New("no space left on device")
+	}
+	return f.File.Write(b)
+}
+
+func (f *failingFile) Sync() error {
+	if f.failSync {
+		return errors.New("failed to sync")
+	}
+	return f.File.Sync()
+}
+
+func TestWAL_write_failure(t *testing.T) {
+	tests := []struct {
+		name string
+		file failingFile
+	}{
+		{
+			name: "Torn write",
+			file: failingFile{tornWrite: true},
+		},
+		{
+			name: "Failed sync",
+			file: failingFile{failSync: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w, err := openWAL(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.close()
+			if err := w.write(walRecord{Op: walOpPut, ID: "a"}, func() {}); err != nil {
+				t.Fatal(err)
+			}
+
+			f := tt.file
+			f.File = w.file.(*os.File)
+			w.file = &f
+			if err := w.write(walRecord{Op: walOpPut, ID: "b"}, func() {}); err == nil {
+				t.Fatal("wal.write() error = nil, want error")
+			}
+			f.tornWrite, f.failSync = false, false
+			if err := w.write(walRecord{Op: walOpPut, ID: "c"}, func() {}); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := openWAL(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.close()
+			var ids []string
+			if err := r.replay(func(r walRecord) error {
+				ids = append(ids, r.ID)
+				return nil
+			}); err != nil {
+				t.Fatalf("wal.replay() error = %v", err)
+			}
+			if diff := cmp.Diff([]string{"a", "c"}, ids); diff != "" {
+				t.Errorf("wal.replay() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,13 +1,76 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/x-color/docdb-in-go/docdb"
+	"github.com/x-color/docdb-in-go/query"
 	"github.com/x-color/docdb-in-go/server"
 )
 
+type indexFlags []docdb.IndexDefinition
+
+func (f *indexFlags) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *indexFlags) Set(v string) error {
+	path, kind, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("index must be path=kind: %s", v)
+	}
+	if docdb.IndexKind(kind) == docdb.IndexCompound {
+		*f = append(*f, docdb.IndexDefinition{Paths: strings.Split(path, ","), Kind: docdb.IndexCompound})
+		return nil
+	}
+	*f = append(*f, docdb.IndexDefinition{Path: path, Kind: docdb.IndexKind(kind)})
+	return nil
+}
+
 func main() {
-	s := server.NewServer("0.0.0.0", 8080)
+	dataDir := flag.String("data", "", "directory to persist documents (in-memory if empty)")
+	idField := flag.String("id-field", "", "document field used as the ID of new documents (generated if empty)")
+	historyVersions := flag.Int("history-versions", 10, "number of prior versions kept per document")
+	historyAge := flag.Duration("history-age", 0, "how long prior versions are kept (forever if 0)")
+	maxQueryTerms := flag.Int("max-query-terms", query.DefaultLimits.MaxTerms, "maximum number of terms in a search query (unlimited if 0)")
+	maxQueryDepth := flag.Int("max-query-depth", query.DefaultLimits.MaxDepth, "maximum nesting depth of a search query (unlimited if 0)")
+	var indexes indexFlags
+	flag.Var(&indexes, "index", "index definition as path=kind, kind is equality, range, unique, text or none, or as path,path=compound (repeatable)")
+	flag.Parse()
+
+	common := []docdb.Option{
+		docdb.WithIDField(*idField),
+		docdb.WithHistoryRetention(*historyVersions, *historyAge),
+		docdb.WithIndexes(indexes...),
+	}
+	opts := append([]docdb.Option{}, common...)
+	collectionsDir := ""
+	if *dataDir != "" {
+		store, err := docdb.NewFileStore(*dataDir, 5*time.Minute)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		opts = append(opts, docdb.WithStore(store))
+		collectionsDir = filepath.Join(*dataDir, "collections")
+	}
+	db, err := docdb.Open(opts...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	collections, err := docdb.NewCollections(collectionsDir, 5*time.Minute, common...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	s := server.NewServer("0.0.0.0", 8080, db, collections, server.WithQueryLimits(query.Limits{
+		MaxTerms: *maxQueryTerms,
+		MaxDepth: *maxQueryDepth,
+	}))
 	log.Println("Start Server")
 	if err := s.Start(); err != nil {
 		log.Println(err)
@@ -0,0 +1,313 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type BoolOp string
+
+const (
+	BoolAnd BoolOp = "AND"
+	BoolOr  BoolOp = "OR"
+	BoolNot BoolOp = "NOT"
+)
+
+func isBoolOp(word string) bool {
+	switch BoolOp(word) {
+	case BoolAnd, BoolOr, BoolNot:
+		return true
+	}
+	return false
+}
+
+// Expr is a node of a parsed query expression. Its queries and
+// sub-expressions are the operands of Op. A NOT node negates the AND of its
+// operands, and has a single one when it is parsed.
+type Expr struct {
+	Op      BoolOp
+	Queries Queries
+	Exprs   []Expr
+}
+
+// Empty reports whether the expression has no operands, as that of an empty
+// query.
+func (e Expr) Empty() bool {
+	return len(e.Queries) == 0 && len(e.Exprs) == 0
+}
+
+func (e Expr) Match(doc map[string]any) bool {
+	switch e.Op {
+	case BoolOr:
+		for _, q := range e.Queries {
+			if q.Match(doc) {
+				return true
+			}
+		}
+		for _, x := range e.Exprs {
+			if x.Match(doc) {
+				return true
+			}
+		}
+		return false
+	case BoolNot:
+		return !Expr{Op: BoolAnd, Queries: e.Queries, Exprs: e.Exprs}.Match(doc)
+	default:
+		if !e.Queries.Match(doc) {
+			return false
+		}
+		for _, x := range e.Exprs {
+			if !x.Match(doc) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Coerce returns the expression whose queries compare values loosely.
+func (e Expr) Coerce() Expr {
+	c := Expr{Op: e.Op, Queries: e.Queries.Coerce()}
+	for _, x := range e.Exprs {
+		c.Exprs = append(c.Exprs, x.Coerce())
+	}
+	return c
+}
+
+// add appends x as an operand. Operands of the same operation are merged, so
+// that `a AND (b AND c)` is a single node.
+func (e *Expr) add(x Expr) {
+	if (x.Op == e.Op && e.Op != BoolNot) || (x.Op == BoolAnd && len(x.Queries) == 1 && len(x.Exprs) == 0) {
+		e.Queries = append(e.Queries, x.Queries...)
+		e.Exprs = append(e.Exprs, x.Exprs...)
+		return
+	}
+	e.Exprs = append(e.Exprs, x)
+}
+
+// simplify returns the single operand of e in place of e.
+func (e Expr) simplify() Expr {
+	if e.Op != BoolNot && len(e.Queries) == 0 && len(e.Exprs) == 1 {
+		return e.Exprs[0]
+	}
+	if e.Op != BoolNot && len(e.Queries) == 1 && len(e.Exprs) == 0 {
+		e.Op = BoolAnd
+	}
+	return e
+}
+
+// parser builds an expression by recursive descent, reading tokens from the
+// lexer as it goes. OR binds looser than AND, which binds looser than NOT.
+type parser struct {
+	lexer  lexer
+	limits Limits
+	tok    token
+	peeked bool
+	terms  int
+	depth  int
+
+	// err is an error of the lexer. The parser sees EOF after it.
+	err error
+}
+
+func (p *parser) peek() token {
+	if !p.peeked {
+		t, err := p.lexer.nextToken()
+		if err != nil {
+			p.err = err
+			t = newToken(kindEOF, "EOF")
+		}
+		p.tok, p.peeked = t, true
+	}
+	return p.tok
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.peeked = false
+	return t
+}
+
+// nest enters a group or a NOT, and the returned function leaves it.
+func (p *parser) nest() (func(), error) {
+	p.depth++
+	if p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+		return nil, fmt.Errorf("%w: nested deeper than %d", ErrTooComplex, p.limits.MaxDepth)
+	}
+	return func() { p.depth-- }, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	e := Expr{Op: BoolOr}
+	for {
+		x, err := p.parseAnd()
+		if err != nil {
+			return Expr{}, err
+		}
+		e.add(x)
+		if t := p.peek(); t.kind != kindBool || BoolOp(t.value) != BoolOr {
+			return e.simplify(), nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	e := Expr{Op: BoolAnd}
+	for {
+		x, err := p.parseNot()
+		if err != nil {
+			return Expr{}, err
+		}
+		e.add(x)
+
+		// Queries without an operator between them are ANDed.
+		t := p.peek()
+		switch {
+		case t.kind == kindBool && BoolOp(t.value) == BoolAnd:
+			p.next()
+		case t.kind == kindKey, t.kind == kindOpen, t.kind == kindBool && BoolOp(t.value) == BoolNot:
+		default:
+			return e.simplify(), nil
+		}
+	}
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	switch t := p.peek(); {
+	case t.kind == kindBool && BoolOp(t.value) == BoolNot:
+		p.next()
+		leave, err := p.nest()
+		if err != nil {
+			return Expr{}, err
+		}
+		defer leave()
+		x, err := p.parseNot()
+		if err != nil {
+			return Expr{}, err
+		}
+		e := Expr{Op: BoolNot}
+		e.add(x)
+		return e, nil
+	case t.kind == kindOpen:
+		p.next()
+		leave, err := p.nest()
+		if err != nil {
+			return Expr{}, err
+		}
+		defer leave()
+		x, err := p.parseOr()
+		if err != nil {
+			return Expr{}, err
+		}
+		if t := p.next(); t.kind != kindClose {
+			return Expr{}, fmt.Errorf("missing )")
+		}
+		return x, nil
+	default:
+		return p.parseQuery()
+	}
+}
+
+func (p *parser) parseQuery() (Expr, error) {
+	q := query{}
+	for p.peek().kind == kindKey {
+		q.Keys = append(q.Keys, p.next().value)
+	}
+	if p.peek().kind == kindOp {
+		q.Op = operation(p.next().value)
+	}
+	var quoted bool
+	if p.peek().kind == kindValue {
+		t := p.next()
+		q.Value = t.value
+		q.Type = valueType(t.value, t.quoted)
+		quoted = t.quoted
+		if err := q.compile(t.quoted); err != nil {
+			return Expr{}, err
+		}
+	}
+	if bounds := string(q.Op); len(bounds) == 2 && (bounds[0] == '[' || bounds[0] == '{') {
+		// The lexer gives a range as its brackets and two values.
+		t := p.next()
+		q.Op = OpeRange
+		q.To = t.value
+		q.ToType = valueType(t.value, t.quoted)
+		q.IncludeFrom = bounds[0] == '['
+		q.IncludeTo = bounds[1] == ']'
+		if q.Type != q.ToType || (q.Type != TypeNumber && q.Type != TypeString) {
+			return Expr{}, fmt.Errorf("invalid range: bounds must be both numbers or both strings")
+		}
+	}
+
+	if len(q.Keys) == 0 || len(string(q.Op)) == 0 || len(q.Value) == 0 {
+		return Expr{}, fmt.Errorf("invalid query")
+	}
+
+	switch {
+	case q.Op == OpeIn:
+		// The lexer gives a list as its first value and the rest of them.
+		e := Expr{Op: BoolOr}
+		for {
+			q.Op = OpeEq
+			if err := p.count(); err != nil {
+				return Expr{}, err
+			}
+			e.add(Expr{Op: BoolAnd, Queries: Queries{q}})
+			if p.peek().kind != kindValue {
+				return e.simplify(), nil
+			}
+			t := p.next()
+			q.Value = t.value
+			q.Type = valueType(t.value, t.quoted)
+		}
+	case len(q.Keys) == 1 && (q.Keys[0] == "has" || q.Keys[0] == "missing") && q.Op == OpeEq && !quoted:
+		// A quoted value is looked up in a field named has or missing.
+		exists := query{Keys: strings.Split(q.Value, "."), Op: OpeExists}
+		for _, key := range exists.Keys {
+			if key == "" {
+				return Expr{}, fmt.Errorf("invalid query")
+			}
+		}
+		if err := p.count(); err != nil {
+			return Expr{}, err
+		}
+		if q.Keys[0] == "missing" {
+			return Expr{Op: BoolNot, Queries: Queries{exists}}, nil
+		}
+		return Expr{Op: BoolAnd, Queries: Queries{exists}}, nil
+	}
+
+	if err := p.count(); err != nil {
+		return Expr{}, err
+	}
+	return Expr{Op: BoolAnd, Queries: Queries{q}}, nil
+}
+
+// count counts a key:value query against the limits.
+func (p *parser) count() error {
+	p.terms++
+	if p.limits.MaxTerms > 0 && p.terms > p.limits.MaxTerms {
+		return fmt.Errorf("%w: more than %d terms", ErrTooComplex, p.limits.MaxTerms)
+	}
+	return nil
+}
+
+// compile compiles the pattern of a regex query, and of an equality query
+// whose unquoted value has wildcards, which it turns into a wildcard query.
+func (q *query) compile(quoted bool) error {
+	switch {
+	case q.Op == OpeRegex:
+		re, err := regexp.Compile(q.Value)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		q.Type = TypeString
+		q.Regexp = re
+	case q.Op == OpeEq && !quoted && strings.ContainsAny(q.Value, "*?"):
+		q.Op = OpeWildcard
+		q.Regexp = wildcard(q.Value)
+	}
+	return nil
+}
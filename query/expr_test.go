@@ -0,0 +1,137 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpr_Match(t *testing.T) {
+	draft := query{Keys: []string{"status"}, Value: "draft", Type: TypeString, Op: OpeEq}
+	review := query{Keys: []string{"status"}, Value: "review", Type: TypeString, Op: OpeEq}
+	cheap := query{Keys: []string{"price"}, Value: "100", Type: TypeNumber, Op: OpeLt}
+
+	tests := []struct {
+		name string
+		e    Expr
+		doc  map[string]any
+		want bool
+	}{
+		{
+			name: "OR matches any operand",
+			e:    Expr{Op: BoolOr, Queries: Queries{draft, review}},
+			doc:  map[string]any{"status": "review"},
+			want: true,
+		},
+		{
+			name: "OR matches no operand",
+			e:    Expr{Op: BoolOr, Queries: Queries{draft, review}},
+			doc:  map[string]any{"status": "published"},
+			want: false,
+		},
+		{
+			name: "NOT negates operand",
+			e:    Expr{Op: BoolNot, Queries: Queries{draft}},
+			doc:  map[string]any{"status": "review"},
+			want: true,
+		},
+		{
+			name: "NOT matches missing field",
+			e:    Expr{Op: BoolNot, Queries: Queries{draft}},
+			doc:  map[string]any{},
+			want: true,
+		},
+		{
+			name: "AND of query and negated expression",
+			e: Expr{
+				Op:      BoolAnd,
+				Queries: Queries{cheap},
+				Exprs:   []Expr{{Op: BoolNot, Queries: Queries{draft}}},
+			},
+			doc:  map[string]any{"status": "draft", "price": 50},
+			want: false,
+		},
+		{
+			name: "OR of query and nested AND",
+			e: Expr{
+				Op:      BoolOr,
+				Queries: Queries{draft},
+				Exprs:   []Expr{{Op: BoolAnd, Queries: Queries{review, cheap}}},
+			},
+			doc:  map[string]any{"status": "review", "price": 50},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.Match(tt.doc); got != tt.want {
+				t.Errorf("Expr.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryWithLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		q         string
+		limits    Limits
+		wantTerms int
+		wantErr   error
+	}{
+		{
+			name:      "Long query is not cut short",
+			q:         "a:1 b:2 c:3 d:4 e:5 f:6 g:7 h:8 i:9 j:10 k:11 l:12",
+			limits:    DefaultLimits,
+			wantTerms: 12,
+		},
+		{
+			name:      "Nested query",
+			q:         `a:1 OR (b:>2 AND NOT (c:"x" OR NOT d:y))`,
+			limits:    DefaultLimits,
+			wantTerms: 4,
+		},
+		{
+			name:    "Too many terms",
+			q:       "a:1 OR b:2 OR c:3",
+			limits:  Limits{MaxTerms: 2},
+			wantErr: ErrTooComplex,
+		},
+		{
+			name:    "Each value of a list is a term",
+			q:       `a:in(1, 2, 3)`,
+			limits:  Limits{MaxTerms: 2},
+			wantErr: ErrTooComplex,
+		},
+		{
+			name:    "Too deeply nested",
+			q:       "a:1 OR (b:2 NOT (c:3))",
+			limits:  Limits{MaxDepth: 2},
+			wantErr: ErrTooComplex,
+		},
+		{
+			name:      "No limits",
+			q:         "((((((a:1))))))",
+			limits:    Limits{},
+			wantTerms: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := ParseQueryWithLimits(tt.q, tt.limits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseQueryWithLimits() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := countTerms(e); got != tt.wantTerms {
+				t.Errorf("ParseQueryWithLimits() has %d terms, want %d", got, tt.wantTerms)
+			}
+		})
+	}
+}
+
+func countTerms(e Expr) int {
+	n := len(e.Queries)
+	for _, x := range e.Exprs {
+		n += countTerms(x)
+	}
+	return n
+}
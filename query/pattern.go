@@ -0,0 +1,42 @@
+package query
+
+import (
+	"regexp"
+	"strings"
+)
+
+// wildcard compiles a pattern, where * matches any text and ? matches a single
+// character, into a regexp which matches whole strings.
+func wildcard(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// Prefix returns the text which every string matched by a wildcard or a regex
+// query starts with. ok is false when there is no such text, as for a regex
+// which is not anchored by ^.
+func (q query) Prefix() (string, bool) {
+	var prefix string
+	switch {
+	case q.Op == OpeWildcard:
+		prefix = q.Value
+		if i := strings.IndexAny(q.Value, "*?"); i >= 0 {
+			prefix = q.Value[:i]
+		}
+	case q.Op == OpeRegex && q.Regexp != nil && strings.HasPrefix(q.Value, "^"):
+		prefix, _ = q.Regexp.LiteralPrefix()
+	}
+	return prefix, prefix != ""
+}
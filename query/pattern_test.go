@@ -0,0 +1,58 @@
+package query
+
+import "testing"
+
+func TestQuery_Prefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      string
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "Prefix",
+			q:      "a:book*",
+			want:   "book",
+			wantOk: true,
+		},
+		{
+			name:   "Wildcards in the middle",
+			q:      "a:b?o*s",
+			want:   "b",
+			wantOk: true,
+		},
+		{
+			name:   "Leading wildcard",
+			q:      "a:?B-12*",
+			wantOk: false,
+		},
+		{
+			name:   "Anchored regex",
+			q:      "a:/^book[A-C]$/",
+			want:   "book",
+			wantOk: true,
+		},
+		{
+			name:   "Unanchored regex",
+			q:      "a:/book/",
+			wantOk: false,
+		},
+		{
+			name:   "Equality",
+			q:      "a:book",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := ParseQuery(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := e.Queries[0].Prefix()
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("query.Prefix() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
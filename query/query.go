@@ -1,8 +1,12 @@
 package query
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
+	"strings"
 )
 
 type kind string
@@ -11,12 +15,16 @@ const (
 	kindKey   kind = "key"
 	kindValue kind = "value"
 	kindOp    kind = "op"
+	kindBool  kind = "bool"
+	kindOpen  kind = "("
+	kindClose kind = ")"
 	kindEOF   kind = "EOF"
 )
 
 type token struct {
-	kind  kind
-	value string
+	kind   kind
+	value  string
+	quoted bool
 }
 
 func newToken(kind kind, value string) token {
@@ -30,24 +38,18 @@ type lexer struct {
 	input string
 	index int
 	kind  kind
-}
 
-func (l lexer) process() ([]token, error) {
-	tokens := make([]token, 0)
-	for len(tokens) < 10 {
-		token, err := l.nextToken()
-		if err != nil {
-			return nil, err
-		}
-		tokens = append(tokens, token)
-		if token.kind == kindEOF {
-			break
-		}
-	}
-	return tokens, nil
+	// pending holds tokens which have been read ahead, such as those of a
+	// range.
+	pending []token
 }
 
 func (l *lexer) nextToken() (token, error) {
+	if len(l.pending) > 0 {
+		t := l.pending[0]
+		l.pending = l.pending[1:]
+		return t, nil
+	}
 	for {
 		switch l.peekChar() {
 		case '"':
@@ -56,20 +58,40 @@ func (l *lexer) nextToken() (token, error) {
 			if err != nil {
 				return token{}, err
 			}
-			return newToken(l.kind, str), nil
+			t := newToken(l.kind, str)
+			t.quoted = true
+			return t, nil
 		case ':':
 			l.readChar()
 			l.switchKind()
 			return l.operator()
 		case '.':
+			if l.kind != kindKey {
+				return newToken(l.kind, l.readWord()), nil
+			}
 			l.readChar()
 		case ' ':
+			// A space ends a value, and the next key follows.
 			l.skipSpace()
-			l.switchKind()
+			l.kind = kindKey
+		case '(':
+			if l.kind != kindKey {
+				return newToken(l.kind, l.readWord()), nil
+			}
+			l.readChar()
+			return newToken(kindOpen, "("), nil
+		case ')':
+			l.readChar()
+			l.kind = kindKey
+			return newToken(kindClose, ")"), nil
 		case 0:
 			return newToken(kindEOF, "EOF"), nil
 		default:
-			return newToken(l.kind, l.readWord()), nil
+			word := l.readWord()
+			if l.kind == kindKey && isBoolOp(word) && l.peekChar() != ':' && l.peekChar() != '.' {
+				return newToken(kindBool, word), nil
+			}
+			return newToken(l.kind, word), nil
 		}
 	}
 }
@@ -88,7 +110,12 @@ func (l *lexer) readWord() string {
 }
 
 func (l lexer) isSpecialChar(ch byte) bool {
-	return ch == '"' || ch == ':' || ch == '.' || ch == ' ' || ch == 0
+	// Dots separate keys, and are a part of values such as 1.5. Parentheses
+	// group queries, so that a value ends at a closing one.
+	if l.kind == kindKey && (ch == '.' || ch == '(') {
+		return true
+	}
+	return ch == '"' || ch == ':' || ch == ')' || ch == ' ' || ch == 0
 }
 
 func (l *lexer) skipSpace() {
@@ -99,12 +126,32 @@ func (l *lexer) skipSpace() {
 
 func (l *lexer) operator() (token, error) {
 	switch l.peekChar() {
-	case '>':
-		l.readChar()
-		return newToken(kindOp, OpeGt.String()), nil
-	case '<':
+	case '>', '<':
+		ch := l.readChar()
+		if l.peekChar() == '=' {
+			l.readChar()
+			return newToken(kindOp, string(ch)+"="), nil
+		}
+		return newToken(kindOp, string(ch)), nil
+	case '!':
+		if !strings.HasPrefix(l.input[l.index+1:], OpeNe.String()) {
+			// A value may start with "!".
+			return newToken(kindOp, OpeEq.String()), nil
+		}
+		l.index += len(OpeNe)
+		return newToken(kindOp, OpeNe.String()), nil
+	case '[', '{':
+		return l.readRange()
+	case 'i':
+		if !strings.HasPrefix(l.input[l.index+1:], OpeIn.String()+"(") {
+			return newToken(kindOp, OpeEq.String()), nil
+		}
+		return l.readList()
+	case '/':
+		return l.readRegex()
+	case '~':
 		l.readChar()
-		return newToken(kindOp, OpeLt.String()), nil
+		return newToken(kindOp, OpeText.String()), nil
 	case 0:
 		return token{}, fmt.Errorf("unexpected character at %d", l.index)
 	default:
@@ -112,6 +159,117 @@ func (l *lexer) operator() (token, error) {
 	}
 }
 
+// readRange reads a range such as [1 TO 5}, where a square bracket includes
+// the bound and a curly one excludes it. It returns the operator and leaves
+// the bounds as pending values.
+func (l *lexer) readRange() (token, error) {
+	open := l.readChar()
+	var bounds []token
+	for i := 0; i < 2; i++ {
+		l.skipSpace()
+		if i == 1 {
+			if !strings.HasPrefix(l.input[l.index+1:], "TO ") {
+				return token{}, fmt.Errorf("missing TO at %d", l.index+1)
+			}
+			l.index += len("TO")
+			l.skipSpace()
+		}
+
+		var t token
+		switch l.peekChar() {
+		case '"':
+			l.readChar()
+			str, err := l.readString()
+			if err != nil {
+				return token{}, err
+			}
+			t = newToken(kindValue, str)
+			t.quoted = true
+		case ' ', ']', '}', 0:
+			return token{}, fmt.Errorf("unexpected character at %d", l.index+1)
+		default:
+			start := l.index + 1
+			for ch := l.peekChar(); ch != ' ' && ch != ']' && ch != '}' && ch != 0; ch = l.peekChar() {
+				l.readChar()
+			}
+			t = newToken(kindValue, l.input[start:l.index+1])
+		}
+		bounds = append(bounds, t)
+	}
+
+	l.skipSpace()
+	end := l.readChar()
+	if end != ']' && end != '}' {
+		return token{}, fmt.Errorf("unexpected character at %d", l.index)
+	}
+	l.pending = append(l.pending, bounds...)
+	return newToken(kindOp, string([]byte{open, end})), nil
+}
+
+// readList reads a list of values such as in("a", 1). It returns the operator
+// and leaves the values as pending ones.
+func (l *lexer) readList() (token, error) {
+	l.index += len(OpeIn) + 1
+	var values []token
+	for {
+		l.skipSpace()
+		var t token
+		switch l.peekChar() {
+		case '"':
+			l.readChar()
+			str, err := l.readString()
+			if err != nil {
+				return token{}, err
+			}
+			t = newToken(kindValue, str)
+			t.quoted = true
+		case ' ', ',', ')', 0:
+			return token{}, fmt.Errorf("unexpected character at %d", l.index+1)
+		default:
+			start := l.index + 1
+			for ch := l.peekChar(); ch != ' ' && ch != ',' && ch != ')' && ch != 0; ch = l.peekChar() {
+				l.readChar()
+			}
+			t = newToken(kindValue, l.input[start:l.index+1])
+		}
+		values = append(values, t)
+
+		l.skipSpace()
+		switch l.readChar() {
+		case ',':
+			continue
+		case ')':
+		default:
+			return token{}, fmt.Errorf("unexpected character at %d", l.index)
+		}
+		// The list is a whole value, so that nothing follows it directly.
+		if ch := l.peekChar(); ch != ' ' && ch != ')' && ch != 0 {
+			return token{}, fmt.Errorf("unexpected character at %d", l.index+1)
+		}
+		l.pending = append(l.pending, values...)
+		return newToken(kindOp, OpeIn.String()), nil
+	}
+}
+
+// readRegex reads a regular expression between slashes, in which a slash is
+// escaped by a backslash. It returns the operator and leaves the expression
+// as a pending value.
+func (l *lexer) readRegex() (token, error) {
+	l.readChar()
+	start := l.index + 1
+	for {
+		switch l.readChar() {
+		case '\\':
+			l.readChar()
+		case '/':
+			l.pending = append(l.pending, newToken(kindValue, l.input[start:l.index]))
+			return newToken(kindOp, OpeRegex.String()), nil
+		case 0:
+			return token{}, fmt.Errorf("unexpected character at %d", l.index)
+		}
+	}
+}
+
 func (l *lexer) readString() (string, error) {
 	l.index++
 	i := l.index
@@ -170,136 +328,402 @@ func (o operation) String() string {
 
 const (
 	OpeEq operation = "="
+	OpeNe operation = "!="
 	OpeLt operation = "<"
+	OpeLe operation = "<="
 	OpeGt operation = ">"
+	OpeGe operation = ">="
+
+	// OpeRange matches values between Value and To. IncludeFrom and
+	// IncludeTo tell whether the bounds themselves match.
+	OpeRange operation = "TO"
+
+	// OpeText matches strings which contain every word of the value.
+	OpeText operation = "~"
+
+	// OpeWildcard matches whole strings by a pattern of the value, where *
+	// matches any text and ? matches a single character.
+	OpeWildcard operation = "*"
+
+	// OpeRegex matches strings which contain a match of the regular
+	// expression of the value.
+	OpeRegex operation = "/"
+
+	// OpeIn matches values which equal any of a list of values. It is parsed
+	// into equality queries ORed together.
+	OpeIn operation = "in"
+
+	// OpeExists matches documents which have a value at the keys, written as
+	// has:a.b. missing:a.b is parsed into its negation.
+	OpeExists operation = "has"
 )
 
+// ValueType is the JSON type of a query value. A quoted value is a string,
+// and an unquoted one is a number, a boolean or null when it reads as one.
+type ValueType string
+
+const (
+	TypeString ValueType = "string"
+	TypeNumber ValueType = "number"
+	TypeBool   ValueType = "bool"
+	TypeNull   ValueType = "null"
+)
+
+func valueType(value string, quoted bool) ValueType {
+	if quoted {
+		return TypeString
+	}
+	switch value {
+	case "true", "false":
+		return TypeBool
+	case "null":
+		return TypeNull
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return TypeNumber
+	}
+	return TypeString
+}
+
 type query struct {
 	Keys  []string
 	Value string
+	Type  ValueType
 	Op    operation
+
+	// To is the upper bound of a range, whose lower bound is Value.
+	To          string
+	ToType      ValueType
+	IncludeFrom bool
+	IncludeTo   bool
+
+	// Regexp is the compiled pattern of a wildcard or a regex query.
+	Regexp *regexp.Regexp
+
+	// Coerce compares values loosely by their text, so that the number 1
+	// equals the string "1".
+	Coerce bool
 }
 
-func (q query) get(doc map[string]any) any {
-	for i, k := range q.Keys {
-		v, ok := doc[k]
-		if !ok {
+// Literal returns the value as its JSON type: float64, string, bool or nil.
+func (q query) Literal() any {
+	return literal(q.Value, q.Type)
+}
+
+func literal(value string, t ValueType) any {
+	switch t {
+	case TypeNumber:
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	case TypeBool:
+		return value == "true"
+	case TypeNull:
+		return nil
+	default:
+		return value
+	}
+}
+
+// Bound is an end of the values which a query matches.
+type Bound struct {
+	Value     any
+	Inclusive bool
+}
+
+// Bounds returns the ends of the values which an ordering query matches. A
+// nil end is open. ok is false for other queries.
+func (q query) Bounds() (lo, hi *Bound, ok bool) {
+	b := &Bound{Value: q.Literal()}
+	switch q.Op {
+	case OpeGt:
+		return b, nil, true
+	case OpeGe:
+		b.Inclusive = true
+		return b, nil, true
+	case OpeLt:
+		return nil, b, true
+	case OpeLe:
+		b.Inclusive = true
+		return nil, b, true
+	case OpeRange:
+		b.Inclusive = q.IncludeFrom
+		return b, &Bound{Value: literal(q.To, q.ToType), Inclusive: q.IncludeTo}, true
+	}
+	return nil, nil, false
+}
+
+func (q query) get(doc map[string]any) []any {
+	return values(doc, q.Keys)
+}
+
+// values returns every value at keys. A numeric key selects an element of an
+// array by position, and any other key is looked up in every element.
+func values(v any, keys []string) []any {
+	if len(keys) == 0 {
+		switch t := v.(type) {
+		case map[string]any:
 			return nil
+		case []any:
+			var vs []any
+			for _, e := range t {
+				vs = append(vs, values(e, nil)...)
+			}
+			return vs
+		default:
+			return []any{t}
 		}
-		if i == len(q.Keys)-1 {
-			break
-		}
-		doc, ok = v.(map[string]any)
+	}
+
+	switch t := v.(type) {
+	case map[string]any:
+		child, ok := t[keys[0]]
 		if !ok {
 			return nil
 		}
+		return values(child, keys[1:])
+	case []any:
+		if i, err := strconv.Atoi(keys[0]); err == nil {
+			if i < 0 || i >= len(t) {
+				return nil
+			}
+			return values(t[i], keys[1:])
+		}
+		var vs []any
+		for _, e := range t {
+			vs = append(vs, values(e, keys)...)
+		}
+		return vs
+	default:
+		return nil
 	}
+}
 
-	v := doc[q.Keys[len(q.Keys)-1]]
-	if _, ok := v.(map[string]any); ok {
-		return nil
+func (q query) Match(doc map[string]any) bool {
+	vs := q.get(doc)
+	if q.Op == OpeExists {
+		return len(vs) > 0
+	}
+	if q.Op == OpeNe {
+		// The field must be there, and none of its values equal the value.
+		eq := q
+		eq.Op = OpeEq
+		for _, v := range vs {
+			if eq.match(v) {
+				return false
+			}
+		}
+		return len(vs) > 0
 	}
 
-	return v
+	for _, v := range vs {
+		if q.match(v) {
+			return true
+		}
+	}
+	return false
 }
 
-func (q query) Match(doc map[string]any) bool {
-	v := q.get(doc)
-	if v == nil {
+func (q query) match(v any) bool {
+	switch q.Op {
+	case OpeText:
+		s, ok := v.(string)
+		return ok && matchText(q.Value, s)
+	case OpeWildcard, OpeRegex:
+		s, ok := v.(string)
+		if q.Coerce {
+			s, ok = fmt.Sprintf("%v", v), true
+		}
+		return ok && q.Regexp.MatchString(s)
+	}
+	if q.Coerce {
+		return q.matchLoose(v)
+	}
+
+	if q.Op == OpeEq {
+		switch lit := q.Literal().(type) {
+		case bool:
+			b, ok := v.(bool)
+			return ok && b == lit
+		case nil:
+			return v == nil
+		}
+	}
+	return q.matchOrder(func(value string, t ValueType) (int, bool) {
+		return compare(v, literal(value, t))
+	})
+}
+
+// matchLoose compares values as before types were carried: equality by their
+// text, and orders numerically when both are numbers or numeric strings.
+func (q query) matchLoose(v any) bool {
+	if q.Op == OpeEq {
+		return q.Value == fmt.Sprintf("%v", v)
+	}
+	return q.matchOrder(func(value string, _ ValueType) (int, bool) {
+		return compareLoose(v, value)
+	})
+}
+
+// matchOrder matches an equality or ordering query by cmp, which compares the
+// value of a document with a value of the query.
+func (q query) matchOrder(cmp func(value string, t ValueType) (int, bool)) bool {
+	c, ok := cmp(q.Value, q.Type)
+	if !ok {
 		return false
 	}
+	switch q.Op {
+	case OpeEq:
+		return c == 0
+	case OpeGt:
+		return c > 0
+	case OpeGe:
+		return c >= 0
+	case OpeLt:
+		return c < 0
+	case OpeLe:
+		return c <= 0
+	case OpeRange:
+		if c < 0 || (c == 0 && !q.IncludeFrom) {
+			return false
+		}
+		c, ok = cmp(q.To, q.ToType)
+		return ok && (c < 0 || (c == 0 && q.IncludeTo))
+	}
+	return false
+}
 
-	if q.Op == OpeEq && q.Value == fmt.Sprintf("%v", v) {
-		return true
+// compare orders v against a number or a string literal. ok is false when v
+// is not of the same JSON type.
+func compare(v, lit any) (int, bool) {
+	switch l := lit.(type) {
+	case float64:
+		f, ok := number(v)
+		if !ok {
+			return 0, false
+		}
+		return compareFloat(f, l), true
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(s, l), true
 	}
+	return 0, false
+}
 
-	r, err := strconv.ParseFloat(q.Value, 64)
+// compareLoose orders v against value numerically when value is numeric and v
+// is a number or a numeric string, and as strings otherwise.
+func compareLoose(v any, value string) (int, bool) {
+	r, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		return false
+		// Non-numeric values are compared as strings.
+		s, ok := v.(string)
+		return strings.Compare(s, value), ok
+	}
+	l, ok := number(v)
+	if !ok {
+		s, isString := v.(string)
+		if !isString {
+			return 0, false
+		}
+		if l, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, false
+		}
+	}
+	return compareFloat(l, r), true
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
 	}
-	var l float64
+	return 0
+}
+
+func number(v any) (float64, bool) {
 	switch t := v.(type) {
 	case float64:
-		l = t
+		return t, true
 	case float32:
-		l = float64(t)
+		return float64(t), true
 	case uint:
-		l = float64(t)
+		return float64(t), true
 	case uint8:
-		l = float64(t)
+		return float64(t), true
 	case uint16:
-		l = float64(t)
+		return float64(t), true
 	case uint32:
-		l = float64(t)
+		return float64(t), true
 	case uint64:
-		l = float64(t)
+		return float64(t), true
 	case int:
-		l = float64(t)
+		return float64(t), true
 	case int8:
-		l = float64(t)
+		return float64(t), true
 	case int16:
-		l = float64(t)
+		return float64(t), true
 	case int32:
-		l = float64(t)
+		return float64(t), true
 	case int64:
-		l = float64(t)
-	case string:
-		l, err = strconv.ParseFloat(t, 64)
-		if err != nil {
-			return false
-		}
-	default:
-		return false
+		return float64(t), true
 	}
-
-	return (q.Op == OpeGt && l > r) || (q.Op == OpeLt && l < r)
+	return 0, false
 }
 
 type Queries []query
 
-func ParseQuery(rq string) (Queries, error) {
+var ErrTooComplex = errors.New("query is too complex")
+
+// Limits bounds the complexity of queries, so that a query beyond them is
+// rejected instead of being cut short. Zero means no limit.
+type Limits struct {
+	// MaxTerms is the number of key:value queries.
+	MaxTerms int
+	// MaxDepth is how deeply parentheses and NOTs are nested.
+	MaxDepth int
+}
+
+var DefaultLimits = Limits{MaxTerms: 100, MaxDepth: 16}
+
+// ParseQuery parses a query expression within DefaultLimits. Queries are
+// ANDed unless OR is put between them, NOT negates the query or the group
+// after it, and parentheses group queries, e.g. `a:1 OR (b:>2 AND NOT c:"x")`.
+func ParseQuery(rq string) (Expr, error) {
+	return ParseQueryWithLimits(rq, DefaultLimits)
+}
+
+func ParseQueryWithLimits(rq string, limits Limits) (Expr, error) {
 	if rq == "" {
-		return nil, nil
+		return Expr{}, nil
 	}
 
-	l := newLexer(rq)
-	tokens, err := l.process()
+	p := parser{lexer: newLexer(rq), limits: limits}
+	e, err := p.parseOr()
+	if p.err != nil {
+		return Expr{}, fmt.Errorf("failed to parse: %w", p.err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse: %w", err)
-	}
-
-	queries := make(Queries, 0)
-	q := query{}
-	for _, token := range tokens {
-		switch token.kind {
-		case kindKey:
-			q.Keys = append(q.Keys, token.value)
-		case kindOp:
-			q.Op = operation(token.value)
-		case kindValue:
-			q.Value = token.value
-			queries = append(queries, q)
-			q = query{}
-		}
+		return Expr{}, err
 	}
-
-	if err := queries.validate(); err != nil {
-		return nil, err
+	if t := p.next(); t.kind != kindEOF {
+		return Expr{}, fmt.Errorf("unexpected %s", t.value)
 	}
 
-	return queries, nil
+	return e, nil
 }
 
-func (qs Queries) validate() error {
-	if len(qs) == 0 {
-		return fmt.Errorf("invalid query")
-	}
-	for _, q := range qs {
-		if len(q.Keys) == 0 || len(string(q.Op)) == 0 || len(q.Value) == 0 {
-			return fmt.Errorf("invalid query")
-		}
+// Coerce returns the queries which compare values loosely.
+func (qs Queries) Coerce() Queries {
+	coerced := make(Queries, len(qs))
+	for i, q := range qs {
+		q.Coerce = true
+		coerced[i] = q
 	}
-	return nil
+	return coerced
 }
 
 func (qs Queries) Match(doc map[string]any) bool {
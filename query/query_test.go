@@ -1,11 +1,19 @@
 package query
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 )
 
+var cmpRegexp = cmp.Comparer(func(a, b *regexp.Regexp) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+})
+
 func TestParseQuery(t *testing.T) {
 	type args struct {
 		q string
@@ -13,7 +21,7 @@ func TestParseQuery(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    Queries
+		want    Expr
 		wantErr bool
 	}{
 		{
@@ -21,13 +29,14 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: "a.b:1",
 			},
-			want: Queries{
+			want: Expr{Op: BoolAnd, Queries: Queries{
 				{
 					Keys:  []string{"a", "b"},
 					Value: "1",
+					Type:  TypeNumber,
 					Op:    OpeEq,
 				},
-			},
+			}},
 			wantErr: false,
 		},
 		{
@@ -35,13 +44,29 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: "a:<10",
 			},
-			want: Queries{
+			want: Expr{Op: BoolAnd, Queries: Queries{
 				{
 					Keys:  []string{"a"},
 					Value: "10",
+					Type:  TypeNumber,
 					Op:    OpeLt,
 				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:~\"sample book\"'",
+			args: args{
+				q: `a:~"sample book"`,
 			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "sample book",
+					Type:  TypeString,
+					Op:    OpeText,
+				},
+			}},
 			wantErr: false,
 		},
 		{
@@ -49,13 +74,14 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: "a:>10",
 			},
-			want: Queries{
+			want: Expr{Op: BoolAnd, Queries: Queries{
 				{
 					Keys:  []string{"a"},
 					Value: "10",
+					Type:  TypeNumber,
 					Op:    OpeGt,
 				},
-			},
+			}},
 			wantErr: false,
 		},
 		{
@@ -63,18 +89,20 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: "a.b:>10 a.c:hello",
 			},
-			want: Queries{
+			want: Expr{Op: BoolAnd, Queries: Queries{
 				{
 					Keys:  []string{"a", "b"},
 					Value: "10",
+					Type:  TypeNumber,
 					Op:    OpeGt,
 				},
 				{
 					Keys:  []string{"a", "c"},
 					Value: "hello",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
-			},
+			}},
 			wantErr: false,
 		},
 		{
@@ -82,13 +110,14 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: `" a ":" hello "`,
 			},
-			want: Queries{
+			want: Expr{Op: BoolAnd, Queries: Queries{
 				{
 					Keys:  []string{" a "},
 					Value: " hello ",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
-			},
+			}},
 			wantErr: false,
 		},
 		{
@@ -96,29 +125,480 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: ` a:hello `,
 			},
-			want: Queries{
+			want: Expr{Op: BoolAnd, Queries: Queries{
 				{
 					Keys:  []string{"a"},
 					Value: "hello",
+					Type:  TypeString,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a.b:1.5 c:\"1\"'",
+			args: args{
+				q: `a.b:1.5 c:"1"`,
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"a", "b"},
+					Value: "1.5",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+				{
+					Keys:  []string{"c"},
+					Value: "1",
+					Type:  TypeString,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'd:true e:null'",
+			args: args{
+				q: `d:true e:null`,
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"d"},
+					Value: "true",
+					Type:  TypeBool,
+					Op:    OpeEq,
+				},
+				{
+					Keys:  []string{"e"},
+					Value: "null",
+					Type:  TypeNull,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:1 OR b:2'",
+			args: args{
+				q: "a:1 OR b:2",
+			},
+			want: Expr{Op: BoolOr, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "1",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+				{
+					Keys:  []string{"b"},
+					Value: "2",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'b:>2 NOT c:x'",
+			args: args{
+				q: "b:>2 NOT c:x",
+			},
+			want: Expr{
+				Op: BoolAnd,
+				Queries: Queries{
+					{
+						Keys:  []string{"b"},
+						Value: "2",
+						Type:  TypeNumber,
+						Op:    OpeGt,
+					},
+				},
+				Exprs: []Expr{
+					{
+						Op: BoolNot,
+						Queries: Queries{
+							{
+								Keys:  []string{"c"},
+								Value: "x",
+								Type:  TypeString,
+								Op:    OpeEq,
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Query: '(a:1 OR b:2)'",
+			args: args{
+				q: "(a:1 OR b:2)",
+			},
+			want: Expr{Op: BoolOr, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "1",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+				{
+					Keys:  []string{"b"},
+					Value: "2",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: '(a:1) AND b:2'",
+			args: args{
+				q: "(a:1) AND b:2",
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "1",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+				{
+					Keys:  []string{"b"},
+					Value: "2",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:1 OR (b:>2 AND NOT c:\"x\")'",
+			args: args{
+				q: `a:1 OR (b:>2 AND NOT c:"x")`,
+			},
+			want: Expr{
+				Op: BoolOr,
+				Queries: Queries{
+					{
+						Keys:  []string{"a"},
+						Value: "1",
+						Type:  TypeNumber,
+						Op:    OpeEq,
+					},
+				},
+				Exprs: []Expr{
+					{
+						Op: BoolAnd,
+						Queries: Queries{
+							{
+								Keys:  []string{"b"},
+								Value: "2",
+								Type:  TypeNumber,
+								Op:    OpeGt,
+							},
+						},
+						Exprs: []Expr{
+							{
+								Op: BoolNot,
+								Queries: Queries{
+									{
+										Keys:  []string{"c"},
+										Value: "x",
+										Type:  TypeString,
+										Op:    OpeEq,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'OR:1' (Keyword as Key)",
+			args: args{
+				q: "OR:1",
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"OR"},
+					Value: "1",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Invalid Query: 'a:1 OR'",
+			args: args{
+				q: "a:1 OR",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Query: '(a:1'",
+			args: args{
+				q: "(a:1",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Query: 'a:1)'",
+			args: args{
+				q: "a:1)",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Query: 'a:>=1 b:!=x'",
+			args: args{
+				q: "a:>=1 b:!=x",
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "1",
+					Type:  TypeNumber,
+					Op:    OpeGe,
+				},
+				{
+					Keys:  []string{"b"},
+					Value: "x",
+					Type:  TypeString,
+					Op:    OpeNe,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:!x'",
+			args: args{
+				q: "a:!x",
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "!x",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:[1 TO 5} b:{\"a\" TO \"m\"]'",
+			args: args{
+				q: `a:[1 TO 5} b:{"a" TO "m"]`,
 			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:        []string{"a"},
+					Value:       "1",
+					Type:        TypeNumber,
+					Op:          OpeRange,
+					To:          "5",
+					ToType:      TypeNumber,
+					IncludeFrom: true,
+				},
+				{
+					Keys:      []string{"b"},
+					Value:     "a",
+					Type:      TypeString,
+					Op:        OpeRange,
+					To:        "m",
+					ToType:    TypeString,
+					IncludeTo: true,
+				},
+			}},
 			wantErr: false,
 		},
+		{
+			name: "Invalid Query: 'a:[1 TO x]'",
+			args: args{
+				q: "a:[1 TO x]",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Query: 'a:[1 5]'",
+			args: args{
+				q: "a:[1 5]",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Query: 'a:[1 TO 5'",
+			args: args{
+				q: "a:[1 TO 5",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
 		{
 			name: "No Query: ''",
 			args: args{
 				q: "",
 			},
-			want:    nil,
+			want:    Expr{},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:bo?k*'",
+			args: args{
+				q: "a:bo?k*",
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:   []string{"a"},
+					Value:  "bo?k*",
+					Type:   TypeString,
+					Op:     OpeWildcard,
+					Regexp: regexp.MustCompile(`(?s)^bo.k.*$`),
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:\"bo?k*\"'",
+			args: args{
+				q: `a:"bo?k*"`,
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "bo?k*",
+					Type:  TypeString,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'a:/^b(o|\\/)k$/ b:1'",
+			args: args{
+				q: `a:/^b(o|\/)k$/ b:1`,
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:   []string{"a"},
+					Value:  `^b(o|\/)k$`,
+					Type:   TypeString,
+					Op:     OpeRegex,
+					Regexp: regexp.MustCompile(`^b(o|\/)k$`),
+				},
+				{
+					Keys:  []string{"b"},
+					Value: "1",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Invalid Query: 'a:/(/'",
+			args: args{
+				q: "a:/(/",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Query: 'a:/b'",
+			args: args{
+				q: "a:/b",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Query: 'a:in(\"x\", 1)'",
+			args: args{
+				q: `a:in("x", 1)`,
+			},
+			want: Expr{Op: BoolOr, Queries: Queries{
+				{
+					Keys:  []string{"a"},
+					Value: "x",
+					Type:  TypeString,
+					Op:    OpeEq,
+				},
+				{
+					Keys:  []string{"a"},
+					Value: "1",
+					Type:  TypeNumber,
+					Op:    OpeEq,
+				},
+			}},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'has:a.b missing:c'",
+			args: args{
+				q: "has:a.b missing:c",
+			},
+			want: Expr{Op: BoolAnd,
+				Queries: Queries{
+					{
+						Keys: []string{"a", "b"},
+						Op:   OpeExists,
+					},
+				},
+				Exprs: []Expr{
+					{Op: BoolNot, Queries: Queries{
+						{
+							Keys: []string{"c"},
+							Op:   OpeExists,
+						},
+					}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Query: 'has:\"a\"'",
+			args: args{
+				q: `has:"a"`,
+			},
+			want: Expr{Op: BoolAnd, Queries: Queries{
+				{
+					Keys:  []string{"has"},
+					Value: "a",
+					Type:  TypeString,
+					Op:    OpeEq,
+				},
+			}},
 			wantErr: false,
 		},
+		{
+			name: "Invalid Query: 'a:in()'",
+			args: args{
+				q: "a:in()",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Query: 'a:in(1)b'",
+			args: args{
+				q: "a:in(1)b",
+			},
+			want:    Expr{},
+			wantErr: true,
+		},
 		{
 			name: "Invalid Query: 'a'",
 			args: args{
 				q: "a",
 			},
-			want:    nil,
+			want:    Expr{},
 			wantErr: true,
 		},
 		{
@@ -126,7 +606,7 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: "a:",
 			},
-			want:    nil,
+			want:    Expr{},
 			wantErr: true,
 		},
 		{
@@ -134,7 +614,7 @@ func TestParseQuery(t *testing.T) {
 			args: args{
 				q: ":1",
 			},
-			want:    nil,
+			want:    Expr{},
 			wantErr: true,
 		},
 	}
@@ -145,7 +625,7 @@ func TestParseQuery(t *testing.T) {
 				t.Errorf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if diff := cmp.Diff(tt.want, got); diff != "" {
+			if diff := cmp.Diff(tt.want, got, cmpRegexp); diff != "" {
 				t.Errorf("ParseQuery() mismatch (-want +got):\n%s", diff)
 			}
 		})
@@ -167,6 +647,7 @@ func TestQuery_Match(t *testing.T) {
 			q: query{
 				Keys:  []string{"a", "b"},
 				Value: "hello",
+				Type:  TypeString,
 				Op:    OpeEq,
 			},
 			args: args{
@@ -183,6 +664,7 @@ func TestQuery_Match(t *testing.T) {
 			q: query{
 				Keys:  []string{"a", "b"},
 				Value: "hello",
+				Type:  TypeString,
 				Op:    OpeEq,
 			},
 			args: args{
@@ -199,6 +681,7 @@ func TestQuery_Match(t *testing.T) {
 			q: query{
 				Keys:  []string{"a", "b"},
 				Value: "hello",
+				Type:  TypeString,
 				Op:    OpeEq,
 			},
 			args: args{
@@ -213,6 +696,7 @@ func TestQuery_Match(t *testing.T) {
 			q: query{
 				Keys:  []string{"a", "b"},
 				Value: "1",
+				Type:  TypeNumber,
 				Op:    OpeGt,
 			},
 			args: args{
@@ -229,6 +713,7 @@ func TestQuery_Match(t *testing.T) {
 			q: query{
 				Keys:  []string{"a", "b"},
 				Value: "1",
+				Type:  TypeNumber,
 				Op:    OpeGt,
 			},
 			args: args{
@@ -245,6 +730,7 @@ func TestQuery_Match(t *testing.T) {
 			q: query{
 				Keys:  []string{"a", "b"},
 				Value: "1",
+				Type:  TypeNumber,
 				Op:    OpeLt,
 			},
 			args: args{
@@ -261,6 +747,7 @@ func TestQuery_Match(t *testing.T) {
 			q: query{
 				Keys:  []string{"a", "b"},
 				Value: "1",
+				Type:  TypeNumber,
 				Op:    OpeLt,
 			},
 			args: args{
@@ -272,6 +759,575 @@ func TestQuery_Match(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "String Query 'name:<bookC'",
+			q: query{
+				Keys:  []string{"name"},
+				Value: "bookC",
+				Type:  TypeString,
+				Op:    OpeLt,
+			},
+			args: args{
+				doc: map[string]any{
+					"name": "bookA",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "String Query 'name:>bookC' (Not Matching Number)",
+			q: query{
+				Keys:  []string{"name"},
+				Value: "bookC",
+				Type:  TypeString,
+				Op:    OpeGt,
+			},
+			args: args{
+				doc: map[string]any{
+					"name": 100,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Text Query 'description:~\"sample BOOK\"'",
+			q: query{
+				Keys:  []string{"description"},
+				Value: "sample BOOK",
+				Type:  TypeString,
+				Op:    OpeText,
+			},
+			args: args{
+				doc: map[string]any{
+					"description": "This is a sample book.",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Text Query 'description:~\"sample novel\"' (Not Matching)",
+			q: query{
+				Keys:  []string{"description"},
+				Value: "sample novel",
+				Type:  TypeString,
+				Op:    OpeText,
+			},
+			args: args{
+				doc: map[string]any{
+					"description": "This is a sample book.",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Text Query of stop words (Not Matching)",
+			q: query{
+				Keys:  []string{"description"},
+				Value: "this is",
+				Type:  TypeString,
+				Op:    OpeText,
+			},
+			args: args{
+				doc: map[string]any{
+					"description": "This is a sample book.",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Array Query 'tags:go'",
+			q: query{
+				Keys:  []string{"tags"},
+				Value: "go",
+				Type:  TypeString,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"tags": []any{"db", "go"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Array Query 'tags:go' (Not Matching)",
+			q: query{
+				Keys:  []string{"tags"},
+				Value: "go",
+				Type:  TypeString,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"tags": []any{"db", "rust"},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Array Query 'authors.name:bob'",
+			q: query{
+				Keys:  []string{"authors", "name"},
+				Value: "bob",
+				Type:  TypeString,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"authors": []any{
+						map[string]any{"name": "alice"},
+						map[string]any{"name": "bob"},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Positional Query 'authors.0.name:bob' (Not Matching)",
+			q: query{
+				Keys:  []string{"authors", "0", "name"},
+				Value: "bob",
+				Type:  TypeString,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"authors": []any{
+						map[string]any{"name": "alice"},
+						map[string]any{"name": "bob"},
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Positional Query 'scores.1:>5'",
+			q: query{
+				Keys:  []string{"scores", "1"},
+				Value: "5",
+				Type:  TypeNumber,
+				Op:    OpeGt,
+			},
+			args: args{
+				doc: map[string]any{
+					"scores": []any{1, 8},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Typed Query 'a:1' (String Value)",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "1",
+				Type:  TypeNumber,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "1",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Typed Query 'a:\"1\"'",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "1",
+				Type:  TypeString,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "1",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Typed Query 'a:true'",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "true",
+				Type:  TypeBool,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": true,
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Typed Query 'a:true' (String Value)",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "true",
+				Type:  TypeBool,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "true",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Typed Query 'a:null'",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "null",
+				Type:  TypeNull,
+				Op:    OpeEq,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": nil,
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Typed Query 'a:>1' (String Value)",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "1",
+				Type:  TypeNumber,
+				Op:    OpeGt,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "2",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Coerced Query 'a:1' (String Value)",
+			q: query{
+				Keys:   []string{"a"},
+				Value:  "1",
+				Type:   TypeNumber,
+				Op:     OpeEq,
+				Coerce: true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "1",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Coerced Query 'a:>1' (String Value)",
+			q: query{
+				Keys:   []string{"a"},
+				Value:  "1",
+				Type:   TypeNumber,
+				Op:     OpeGt,
+				Coerce: true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "2",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Query 'a:>=2'",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "2",
+				Type:  TypeNumber,
+				Op:    OpeGe,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": 2,
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Query 'a:<=2' (Not Matching)",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "2",
+				Type:  TypeNumber,
+				Op:    OpeLe,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": 3,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Query 'a:!=go'",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "go",
+				Type:  TypeString,
+				Op:    OpeNe,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": []any{"rust", "db"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Query 'a:!=go' (Any Element Equals)",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "go",
+				Type:  TypeString,
+				Op:    OpeNe,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": []any{"go", "db"},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Query 'a:!=go' (Key Does Not Exists)",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "go",
+				Type:  TypeString,
+				Op:    OpeNe,
+			},
+			args: args{
+				doc: map[string]any{
+					"b": "db",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Query 'a:!=1' (String Value)",
+			q: query{
+				Keys:  []string{"a"},
+				Value: "1",
+				Type:  TypeNumber,
+				Op:    OpeNe,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "1",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Query 'a:[1 TO 5]' (Upper Bound)",
+			q: query{
+				Keys:        []string{"a"},
+				Value:       "1",
+				Type:        TypeNumber,
+				Op:          OpeRange,
+				To:          "5",
+				ToType:      TypeNumber,
+				IncludeFrom: true,
+				IncludeTo:   true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": 5,
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Query 'a:[1 TO 5}' (Upper Bound)",
+			q: query{
+				Keys:        []string{"a"},
+				Value:       "1",
+				Type:        TypeNumber,
+				Op:          OpeRange,
+				To:          "5",
+				ToType:      TypeNumber,
+				IncludeFrom: true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": 5,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Query 'a:{1 TO 5]' (Lower Bound)",
+			q: query{
+				Keys:      []string{"a"},
+				Value:     "1",
+				Type:      TypeNumber,
+				Op:        OpeRange,
+				To:        "5",
+				ToType:    TypeNumber,
+				IncludeTo: true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": 1,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Query 'a:[a TO m]'",
+			q: query{
+				Keys:        []string{"a"},
+				Value:       "a",
+				Type:        TypeString,
+				Op:          OpeRange,
+				To:          "m",
+				ToType:      TypeString,
+				IncludeFrom: true,
+				IncludeTo:   true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "go",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Coerced Query 'a:[1 TO 5]' (String Value)",
+			q: query{
+				Keys:        []string{"a"},
+				Value:       "1",
+				Type:        TypeNumber,
+				Op:          OpeRange,
+				To:          "5",
+				ToType:      TypeNumber,
+				IncludeFrom: true,
+				IncludeTo:   true,
+				Coerce:      true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "3",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Wildcard Query 'a:?B-12*'",
+			q: query{
+				Keys:   []string{"a"},
+				Value:  "?B-12*",
+				Type:   TypeString,
+				Op:     OpeWildcard,
+				Regexp: wildcard("?B-12*"),
+			},
+			args: args{
+				doc: map[string]any{
+					"a": []any{"B-12", "XB-123"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Wildcard Query 'a:1*' does not match number",
+			q: query{
+				Keys:   []string{"a"},
+				Value:  "1*",
+				Type:   TypeString,
+				Op:     OpeWildcard,
+				Regexp: wildcard("1*"),
+			},
+			args: args{
+				doc: map[string]any{
+					"a": 100,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Coerced Wildcard Query 'a:1*'",
+			q: query{
+				Keys:   []string{"a"},
+				Value:  "1*",
+				Type:   TypeString,
+				Op:     OpeWildcard,
+				Regexp: wildcard("1*"),
+				Coerce: true,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": 100,
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Regex Query 'a:/^book[A-C]$/'",
+			q: query{
+				Keys:   []string{"a"},
+				Value:  "^book[A-C]$",
+				Type:   TypeString,
+				Op:     OpeRegex,
+				Regexp: regexp.MustCompile("^book[A-C]$"),
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "bookD",
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Regex Query 'a:/ok/' matches within string",
+			q: query{
+				Keys:   []string{"a"},
+				Value:  "ok",
+				Type:   TypeString,
+				Op:     OpeRegex,
+				Regexp: regexp.MustCompile("ok"),
+			},
+			args: args{
+				doc: map[string]any{
+					"a": "bookD",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Exists Query 'has:a.b'",
+			q: query{
+				Keys: []string{"a", "b"},
+				Op:   OpeExists,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": map[string]any{"b": nil},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Exists Query 'has:a' (Object)",
+			q: query{
+				Keys: []string{"a"},
+				Op:   OpeExists,
+			},
+			args: args{
+				doc: map[string]any{
+					"a": map[string]any{"b": 1},
+				},
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -298,6 +1354,7 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "hello",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
 			},
@@ -316,6 +1373,7 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "hello",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
 			},
@@ -334,6 +1392,7 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "hello",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
 			},
@@ -350,6 +1409,7 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "1",
+					Type:  TypeNumber,
 					Op:    OpeGt,
 				},
 			},
@@ -368,6 +1428,7 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "1",
+					Type:  TypeNumber,
 					Op:    OpeGt,
 				},
 			},
@@ -386,6 +1447,7 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "1",
+					Type:  TypeNumber,
 					Op:    OpeLt,
 				},
 			},
@@ -404,6 +1466,7 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "1",
+					Type:  TypeNumber,
 					Op:    OpeLt,
 				},
 			},
@@ -422,11 +1485,13 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "1",
+					Type:  TypeNumber,
 					Op:    OpeEq,
 				},
 				{
 					Keys:  []string{"b", "c"},
 					Value: "hello",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
 			},
@@ -448,11 +1513,13 @@ func TestQueries_Match(t *testing.T) {
 				{
 					Keys:  []string{"a", "b"},
 					Value: "1",
+					Type:  TypeNumber,
 					Op:    OpeEq,
 				},
 				{
 					Keys:  []string{"b", "c"},
 					Value: "hello",
+					Type:  TypeString,
 					Op:    OpeEq,
 				},
 			},
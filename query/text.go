@@ -0,0 +1,47 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "for": true, "if": true, "in": true,
+	"into": true, "is": true, "it": true, "no": true, "not": true, "of": true,
+	"on": true, "or": true, "such": true, "that": true, "the": true,
+	"their": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// Tokenize splits text into lowercased words, leaving out stop words.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		if !stopWords[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// matchText reports whether text contains every token of the query.
+func matchText(query, text string) bool {
+	want := Tokenize(query)
+	if len(want) == 0 {
+		return false
+	}
+	has := make(map[string]bool)
+	for _, t := range Tokenize(text) {
+		has[t] = true
+	}
+	for _, t := range want {
+		if !has[t] {
+			return false
+		}
+	}
+	return true
+}
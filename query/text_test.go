@@ -0,0 +1,53 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "Lowercase words",
+			text: "Sample Book",
+			want: []string{"sample", "book"},
+		},
+		{
+			name: "Split at punctuation",
+			text: "go-cmp, docdb.v2!",
+			want: []string{"go", "cmp", "docdb", "v2"},
+		},
+		{
+			name: "Leave out stop words",
+			text: "this is a sample book of the year",
+			want: []string{"sample", "book", "year"},
+		},
+		{
+			name: "Keep repeated words",
+			text: "book book",
+			want: []string{"book", "book"},
+		},
+		{
+			name: "Non-ASCII letters",
+			text: "Café au lait",
+			want: []string{"café", "au", "lait"},
+		},
+		{
+			name: "Only stop words",
+			text: "this is",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, Tokenize(tt.text)); diff != "" {
+				t.Errorf("Tokenize() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+func (s Server) CreateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Name string `json:"name"`
+	}{}
+	dc := json.NewDecoder(r.Body)
+	if err := dc.Decode(&body); err != nil {
+		errResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := s.collections.Create(body.Name); err != nil {
+		switch {
+		case errors.Is(err, docdb.ErrInvalidName):
+			errResponse(w, http.StatusBadRequest, err)
+		case errors.Is(err, docdb.ErrAlreadyExists):
+			errResponse(w, http.StatusConflict, err)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	response(w, http.StatusCreated, map[string]any{
+		"name": body.Name,
+	})
+}
+
+func (s Server) ListCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	names := s.collections.List()
+	response(w, http.StatusOK, map[string]any{
+		"collections": names,
+		"count":       len(names),
+	})
+}
+
+func (s Server) DropCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["collection"]
+
+	if err := s.collections.Drop(name); err != nil {
+		switch {
+		case errors.Is(err, docdb.ErrNotFound):
+			errResponse(w, http.StatusNotFound, nil)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+func TestServer_Collections(t *testing.T) {
+	type request struct {
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantRes  map[string]any
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "Create, list and drop collections",
+			requests: []request{
+				{method: "POST", path: "/collections", body: `{"name":"books"}`, wantCode: http.StatusCreated},
+				{method: "POST", path: "/collections", body: `{"name":"users"}`, wantCode: http.StatusCreated},
+				{method: "POST", path: "/collections", body: `{"name":"books"}`, wantCode: http.StatusConflict},
+				{method: "POST", path: "/collections", body: `{"name":"a/b"}`, wantCode: http.StatusBadRequest},
+				{
+					method:   "GET",
+					path:     "/collections",
+					wantCode: http.StatusOK,
+					wantRes: map[string]any{
+						"collections": []any{"books", "users"},
+						"count":       float64(2),
+					},
+				},
+				{method: "DELETE", path: "/collections/users", wantCode: http.StatusNoContent},
+				{method: "DELETE", path: "/collections/users", wantCode: http.StatusNotFound},
+				{
+					method:   "GET",
+					path:     "/collections",
+					wantCode: http.StatusOK,
+					wantRes: map[string]any{
+						"collections": []any{"books"},
+						"count":       float64(1),
+					},
+				},
+			},
+		},
+		{
+			name: "Documents are scoped to collection",
+			requests: []request{
+				{method: "POST", path: "/collections", body: `{"name":"books"}`, wantCode: http.StatusCreated},
+				{method: "POST", path: "/collections", body: `{"name":"users"}`, wantCode: http.StatusCreated},
+				{method: "POST", path: "/collections/books/docs", body: `{"name":"x"}`, wantCode: http.StatusCreated},
+				{method: "GET", path: "/collections/books/docs?q=name:x", wantCode: http.StatusOK},
+				{method: "GET", path: "/collections/users/docs?q=name:x", wantCode: http.StatusNotFound},
+				{method: "GET", path: "/docs?q=name:x", wantCode: http.StatusNotFound},
+				{method: "POST", path: "/collections/orders/docs", body: `{"name":"x"}`, wantCode: http.StatusNotFound},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := docdb.NewCollections("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer("localhost", 0, docdb.NewDocDB(), cs)
+
+			for _, rq := range tt.requests {
+				req, err := http.NewRequest(rq.method, rq.path, bytes.NewBufferString(rq.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				rr := httptest.NewRecorder()
+				s.server.Handler.ServeHTTP(rr, req)
+
+				if rr.Code != rq.wantCode {
+					t.Errorf("%s %s returned wrong status code: got %v want %v", rq.method, rq.path, rr.Code, rq.wantCode)
+				}
+
+				if rq.wantRes == nil {
+					continue
+				}
+				res := make(map[string]any)
+				if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+					t.Errorf("handler returned invalid body: got %v", rr.Body.String())
+				}
+				if diff := cmp.Diff(rq.wantRes, res); diff != "" {
+					t.Errorf("%s %s mismatch (-want +got):\n%s", rq.method, rq.path, diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+func etag(rev uint64) string {
+	return fmt.Sprintf(`"%d"`, rev)
+}
+
+// parseETags returns the revisions listed in an If-Match or If-None-Match
+// header. It returns nil for "*". Weak or malformed tags are returned as
+// revision 0, which no document ever has, so that they never match.
+func parseETags(h string) []uint64 {
+	if strings.TrimSpace(h) == "*" {
+		return nil
+	}
+
+	revs := make([]uint64, 0)
+	for _, tag := range strings.Split(h, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			revs = append(revs, 0)
+			continue
+		}
+		rev, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+		if err != nil {
+			rev = 0
+		}
+		revs = append(revs, rev)
+	}
+	return revs
+}
+
+func conditions(r *http.Request) []docdb.Condition {
+	var conds []docdb.Condition
+	if h := r.Header.Get("If-Match"); h != "" {
+		conds = append(conds, docdb.IfMatch(parseETags(h)...))
+	}
+	if h := r.Header.Get("If-None-Match"); h != "" {
+		conds = append(conds, docdb.IfNoneMatch(parseETags(h)...))
+	}
+	return conds
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+var errInvalidAsOf = errors.New("asOf must be a revision or an RFC3339 time")
+
+func (s Server) DocumentHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	versions, err := db.History(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, docdb.ErrNotFound):
+			errResponse(w, http.StatusNotFound, nil)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	response(w, http.StatusOK, map[string]any{
+		"id":      id,
+		"history": versions,
+		"count":   len(versions),
+	})
+}
+
+func (s Server) DocumentDiffHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	from, err := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		errResponse(w, http.StatusBadRequest, fmt.Errorf("invalid from revision: %w", err))
+		return
+	}
+	to, err := strconv.ParseUint(r.URL.Query().Get("to"), 10, 64)
+	if err != nil {
+		errResponse(w, http.StatusBadRequest, fmt.Errorf("invalid to revision: %w", err))
+		return
+	}
+
+	ops, err := db.Diff(id, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, docdb.ErrNotFound):
+			errResponse(w, http.StatusNotFound, nil)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	response(w, http.StatusOK, map[string]any{
+		"id":    id,
+		"from":  from,
+		"to":    to,
+		"patch": ops,
+	})
+}
+
+func getAsOf(db *docdb.DocDB, id, asOf string) (map[string]any, uint64, error) {
+	if rev, err := strconv.ParseUint(asOf, 10, 64); err == nil {
+		doc, err := db.GetRevision(id, rev)
+		return doc, rev, err
+	}
+	t, err := time.Parse(time.RFC3339, asOf)
+	if err != nil {
+		return nil, 0, errInvalidAsOf
+	}
+	return db.GetAsOf(id, t)
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+func TestServer_DocumentHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     func(id string, first, second uint64) string
+		delete   bool
+		wantCode int
+		wantRes  map[string]any
+	}{
+		{
+			name: "Get history",
+			path: func(id string, first, second uint64) string {
+				return "/docs/" + id + "/history"
+			},
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"count": float64(2),
+			},
+		},
+		{
+			name: "Get document as of revision",
+			path: func(id string, first, second uint64) string {
+				return fmt.Sprintf("/docs/%s?asOf=%d", id, first)
+			},
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"price": float64(100),
+			},
+		},
+		{
+			name: "Get document as of time",
+			path: func(id string, first, second uint64) string {
+				return "/docs/" + id + "?asOf=2000-01-01T00:00:00Z"
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "Get document as of invalid value",
+			path: func(id string, first, second uint64) string {
+				return "/docs/" + id + "?asOf=yesterday"
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Diff revisions",
+			path: func(id string, first, second uint64) string {
+				return fmt.Sprintf("/docs/%s/diff?from=%d&to=%d", id, first, second)
+			},
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"patch": []any{
+					map[string]any{"op": "replace", "path": "/price", "value": float64(200)},
+				},
+			},
+		},
+		{
+			name: "Diff unknown revision",
+			path: func(id string, first, second uint64) string {
+				return fmt.Sprintf("/docs/%s/diff?from=%d&to=%d", id, first, second+100)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "Get history of deleted document",
+			path: func(id string, first, second uint64) string {
+				return "/docs/" + id + "/history"
+			},
+			delete:   true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "Diff revisions of deleted document",
+			path: func(id string, first, second uint64) string {
+				return fmt.Sprintf("/docs/%s/diff?from=%d&to=%d", id, first, second)
+			},
+			delete:   true,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := docdb.NewCollections("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer("localhost", 0, docdb.NewDocDB(), cs)
+			id, err := s.docdb.Add(map[string]any{"price": 100})
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+			_, first, err := s.docdb.GetWithRevision(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := s.docdb.Update(id, map[string]any{"price": 200})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.delete {
+				if err := s.docdb.Delete(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			req, err := http.NewRequest("GET", tt.path(id, first, second), bytes.NewBuffer(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+			if tt.wantRes == nil {
+				return
+			}
+
+			res := make(map[string]any)
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Errorf("handler returned invalid body: got %v", rr.Body.String())
+			}
+			for k, want := range tt.wantRes {
+				if diff := cmp.Diff(want, res[k]); diff != "" {
+					t.Errorf("%s mismatch (-want +got):\n%s", k, diff)
+				}
+			}
+		})
+	}
+}
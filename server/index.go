@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+func (s Server) CreateIndexHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	body := struct {
+		Path  string          `json:"path"`
+		Paths []string        `json:"paths"`
+		Kind  docdb.IndexKind `json:"kind"`
+	}{}
+	dc := json.NewDecoder(r.Body)
+	if err := dc.Decode(&body); err != nil {
+		errResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	if body.Kind == docdb.IndexCompound || (body.Kind == "" && len(body.Paths) > 0) {
+		body.Kind = docdb.IndexCompound
+		err = db.CreateCompoundIndex(body.Paths...)
+	} else {
+		err = db.CreateIndex(body.Path, body.Kind)
+	}
+	if err != nil {
+		var ue *docdb.UniqueError
+		switch {
+		case errors.As(err, &ue):
+			uniqueResponse(w, ue)
+		case errors.Is(err, docdb.ErrInvalidIndex):
+			errResponse(w, http.StatusBadRequest, err)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	res := map[string]any{
+		"path": body.Path,
+		"kind": body.Kind,
+	}
+	if body.Kind == docdb.IndexCompound {
+		res = map[string]any{
+			"paths": body.Paths,
+			"kind":  body.Kind,
+		}
+	}
+	response(w, http.StatusAccepted, res)
+}
+
+func (s Server) ListIndexesHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	indexes := db.Indexes()
+	response(w, http.StatusOK, map[string]any{
+		"indexes": indexes,
+		"count":   len(indexes),
+	})
+}
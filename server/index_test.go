@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+func TestServer_Indexes(t *testing.T) {
+	type request struct {
+		method   string
+		path     string
+		body     string
+		mimeType string
+		wantCode int
+		wantRes  map[string]any
+	}
+	tests := []struct {
+		name     string
+		opts     []docdb.Option
+		requests []request
+	}{
+		{
+			name: "Create index",
+			requests: []request{
+				{
+					method:   "POST",
+					path:     "/indexes",
+					body:     `{"path":"detail.description","kind":"none"}`,
+					wantCode: http.StatusAccepted,
+					wantRes: map[string]any{
+						"path": "detail.description",
+						"kind": "none",
+					},
+				},
+				{
+					method:   "POST",
+					path:     "/indexes",
+					body:     `{"paths":["category","detail.price"]}`,
+					wantCode: http.StatusAccepted,
+					wantRes: map[string]any{
+						"paths": []any{"category", "detail.price"},
+						"kind":  "compound",
+					},
+				},
+				{method: "POST", path: "/indexes", body: `{"paths":["category"],"kind":"compound"}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/indexes", body: `{"path":"category","kind":"compound"}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/indexes", body: `{"path":"name","kind":"fulltext"}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/indexes", body: `{"kind":"range"}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/indexes", body: `{`, wantCode: http.StatusBadRequest},
+			},
+		},
+		{
+			name: "List indexes",
+			opts: []docdb.Option{
+				docdb.WithIndexes(
+					docdb.IndexDefinition{Path: "name", Kind: docdb.IndexEquality},
+					docdb.IndexDefinition{Path: "detail.description", Kind: docdb.IndexNone},
+					docdb.IndexDefinition{Paths: []string{"category", "detail.price"}, Kind: docdb.IndexCompound},
+				),
+			},
+			requests: []request{
+				{
+					method:   "GET",
+					path:     "/indexes",
+					wantCode: http.StatusOK,
+					wantRes: map[string]any{
+						"indexes": []any{
+							map[string]any{"path": "detail.description", "kind": "none", "ready": true},
+							map[string]any{"path": "name", "kind": "equality", "ready": true},
+							map[string]any{"paths": []any{"category", "detail.price"}, "kind": "compound", "ready": true},
+						},
+						"count": float64(3),
+					},
+				},
+			},
+		},
+		{
+			name: "Search on unindexed path",
+			opts: []docdb.Option{
+				docdb.WithIndexes(docdb.IndexDefinition{Path: "detail", Kind: docdb.IndexNone}),
+			},
+			requests: []request{
+				{method: "POST", path: "/docs", body: `{"detail":{"description":"this is sample book"}}`, wantCode: http.StatusCreated},
+				{method: "GET", path: "/docs?q=detail.description:%22this%20is%20sample%20book%22", wantCode: http.StatusOK},
+				{method: "GET", path: "/docs?q=detail.description:%22other%22", wantCode: http.StatusNotFound},
+			},
+		},
+		{
+			name: "Unique index",
+			opts: []docdb.Option{
+				docdb.WithIndexes(docdb.IndexDefinition{Path: "email", Kind: docdb.IndexUnique}),
+			},
+			requests: []request{
+				{method: "PUT", path: "/docs/a", body: `{"email":"a@example.com","name":"alice"}`, wantCode: http.StatusCreated},
+				{
+					method:   "POST",
+					path:     "/docs",
+					body:     `{"email":"a@example.com"}`,
+					wantCode: http.StatusConflict,
+					wantRes: map[string]any{
+						"error": "email a@example.com is already used by a",
+						"field": "email",
+						"id":    "a",
+					},
+				},
+				{method: "PUT", path: "/docs/b", body: `{"email":"b@example.com","name":"alice"}`, wantCode: http.StatusCreated},
+				{method: "PUT", path: "/docs/b", body: `{"email":"a@example.com"}`, wantCode: http.StatusConflict},
+				{method: "PATCH", path: "/docs/b", body: `{"email":"a@example.com"}`, mimeType: "application/merge-patch+json", wantCode: http.StatusConflict},
+				{
+					method:   "POST",
+					path:     "/indexes",
+					body:     `{"path":"name","kind":"unique"}`,
+					wantCode: http.StatusConflict,
+					wantRes: map[string]any{
+						"error": "name alice is already used by a",
+						"field": "name",
+						"id":    "a",
+					},
+				},
+			},
+		},
+		{
+			name: "Full-text index",
+			opts: []docdb.Option{
+				docdb.WithIndexes(docdb.IndexDefinition{Path: "description", Kind: docdb.IndexText}),
+			},
+			requests: []request{
+				{method: "PUT", path: "/docs/a", body: `{"description":"sample book"}`, wantCode: http.StatusCreated},
+				{method: "PUT", path: "/docs/b", body: `{"description":"sample music"}`, wantCode: http.StatusCreated},
+				{
+					method:   "GET",
+					path:     "/docs?q=description:~%22Book%22",
+					wantCode: http.StatusOK,
+					wantRes: map[string]any{
+						"documents": []any{
+							map[string]any{
+								"id":       "a",
+								"document": map[string]any{"description": "sample book"},
+								"score":    0.6931471805599453,
+							},
+						},
+						"count": float64(1),
+					},
+				},
+				{method: "GET", path: "/docs?q=description:~%22sample%20novel%22", wantCode: http.StatusNotFound},
+			},
+		},
+		{
+			name: "Indexes of collection",
+			requests: []request{
+				{method: "POST", path: "/collections", body: `{"name":"books"}`, wantCode: http.StatusCreated},
+				{method: "POST", path: "/collections/books/indexes", body: `{"path":"name","kind":"none"}`, wantCode: http.StatusAccepted},
+				{method: "POST", path: "/collections/users/indexes", body: `{"path":"name","kind":"none"}`, wantCode: http.StatusNotFound},
+				{
+					method:   "GET",
+					path:     "/indexes",
+					wantCode: http.StatusOK,
+					wantRes: map[string]any{
+						"indexes": []any{},
+						"count":   float64(0),
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := docdb.NewCollections("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer("localhost", 0, docdb.NewDocDB(tt.opts...), cs)
+
+			for _, rq := range tt.requests {
+				req, err := http.NewRequest(rq.method, rq.path, bytes.NewBufferString(rq.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rq.mimeType != "" {
+					req.Header.Set("Content-Type", rq.mimeType)
+				}
+
+				rr := httptest.NewRecorder()
+				s.server.Handler.ServeHTTP(rr, req)
+
+				if rr.Code != rq.wantCode {
+					t.Errorf("%s %s returned wrong status code: got %v want %v", rq.method, rq.path, rr.Code, rq.wantCode)
+				}
+
+				if rq.wantRes == nil {
+					continue
+				}
+				res := make(map[string]any)
+				if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+					t.Errorf("handler returned invalid body: got %v", rr.Body.String())
+				}
+				if diff := cmp.Diff(rq.wantRes, res); diff != "" {
+					t.Errorf("%s %s mismatch (-want +got):\n%s", rq.method, rq.path, diff)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"os"
 	"os/signal"
@@ -19,16 +20,46 @@ import (
 type middleware func(http.HandlerFunc) http.HandlerFunc
 
 type Server struct {
-	docdb  *docdb.DocDB
-	server *http.Server
-	wait   time.Duration
+	docdb       *docdb.DocDB
+	collections *docdb.Collections
+	server      *http.Server
+	wait        time.Duration
+	limits      query.Limits
+}
+
+type Option func(*Server)
+
+// WithQueryLimits bounds the complexity of search queries.
+func WithQueryLimits(limits query.Limits) Option {
+	return func(s *Server) {
+		s.limits = limits
+	}
 }
 
 func (s Server) defaultHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (s Server) documents(w http.ResponseWriter, r *http.Request) (*docdb.DocDB, bool) {
+	name, ok := mux.Vars(r)["collection"]
+	if !ok {
+		return s.docdb, true
+	}
+
+	c, err := s.collections.Get(name)
+	if err != nil {
+		errResponse(w, http.StatusNotFound, fmt.Errorf("collection %s is not found", name))
+		return nil, false
+	}
+	return c.DocDB, true
+}
+
 func (s Server) AddDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
 	doc := make(map[string]any)
 	dc := json.NewDecoder(r.Body)
 	if err := dc.Decode(&doc); err != nil {
@@ -36,26 +67,51 @@ func (s Server) AddDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := s.docdb.Add(doc)
+	if err := setTTL(r, doc); err != nil {
+		errResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, rev, err := db.AddWithRevision(doc)
 	if err != nil {
-		errResponse(w, http.StatusInternalServerError, nil)
+		var ue *docdb.UniqueError
+		switch {
+		case errors.As(err, &ue):
+			uniqueResponse(w, ue)
+		case errors.Is(err, docdb.ErrInvalidID), errors.Is(err, docdb.ErrInvalidExpiry):
+			errResponse(w, http.StatusBadRequest, err)
+		case errors.Is(err, docdb.ErrAlreadyExists):
+			errResponse(w, http.StatusConflict, err)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
 		return
 	}
 
+	w.Header().Set("ETag", etag(rev))
 	response(w, http.StatusCreated, map[string]any{
 		"id": id,
 	})
 }
 
 func (s Server) SearchDocumentsHandler(w http.ResponseWriter, r *http.Request) {
-	q, err := query.ParseQuery(r.URL.Query().Get("q"))
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	q, err := query.ParseQueryWithLimits(r.URL.Query().Get("q"), s.limits)
 	if err != nil {
 		log.Printf("(id=%v) Not found document: %v", r.Context().Value(ctxKeyID), err)
 		errResponse(w, http.StatusBadRequest, err)
 		return
 	}
 
-	docs, err := s.docdb.Search(q)
+	if r.URL.Query().Get("coerce") == "true" {
+		q = q.Coerce()
+	}
+
+	docs, err := db.Search(q)
 	if err != nil {
 		errResponse(w, http.StatusInternalServerError, nil)
 		return
@@ -72,12 +128,27 @@ func (s Server) SearchDocumentsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s Server) GetDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	doc, err := s.docdb.Get(id)
+	asOf := r.URL.Query().Get("asOf")
+	var doc map[string]any
+	var rev uint64
+	var err error
+	if asOf != "" {
+		doc, rev, err = getAsOf(db, id, asOf)
+	} else {
+		doc, rev, err = db.GetWithRevision(id)
+	}
 	if err != nil {
 		switch {
+		case errors.Is(err, errInvalidAsOf):
+			errResponse(w, http.StatusBadRequest, err)
 		case errors.Is(err, docdb.ErrNotFound):
 			errResponse(w, http.StatusNotFound, nil)
 		default:
@@ -86,9 +157,166 @@ func (s Server) GetDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", etag(rev))
+	if h := r.Header.Get("If-None-Match"); asOf == "" && h != "" && !docdb.IfNoneMatch(parseETags(h)...)(rev, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	response(w, http.StatusOK, doc)
 }
 
+func (s Server) UpdateDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	doc := make(map[string]any)
+	dc := json.NewDecoder(r.Body)
+	if err := dc.Decode(&doc); err != nil {
+		errResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := setTTL(r, doc); err != nil {
+		errResponse(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rev, created, err := db.Upsert(id, doc, conditions(r)...)
+	if err != nil {
+		var ue *docdb.UniqueError
+		switch {
+		case errors.As(err, &ue):
+			uniqueResponse(w, ue)
+		case errors.Is(err, docdb.ErrInvalidID), errors.Is(err, docdb.ErrInvalidExpiry):
+			errResponse(w, http.StatusBadRequest, err)
+		case errors.Is(err, docdb.ErrConflict):
+			errResponse(w, http.StatusPreconditionFailed, nil)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", etag(rev))
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	response(w, code, map[string]any{
+		"id": id,
+	})
+}
+
+func (s Server) PatchDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		errResponse(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+
+	var rev uint64
+	conds := conditions(r)
+	dc := json.NewDecoder(r.Body)
+	switch mediaType {
+	case "application/merge-patch+json":
+		patch := make(map[string]any)
+		if err := dc.Decode(&patch); err != nil {
+			errResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		rev, err = db.MergePatch(id, patch, conds...)
+	case "application/json-patch+json":
+		ops := make([]docdb.PatchOperation, 0)
+		if err := dc.Decode(&ops); err != nil {
+			errResponse(w, http.StatusBadRequest, err)
+			return
+		}
+		rev, err = db.JSONPatch(id, ops, conds...)
+	default:
+		errResponse(w, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type: %s", mediaType))
+		return
+	}
+	if err != nil {
+		var ue *docdb.UniqueError
+		switch {
+		case errors.As(err, &ue):
+			uniqueResponse(w, ue)
+		case errors.Is(err, docdb.ErrNotFound):
+			errResponse(w, http.StatusNotFound, nil)
+		case errors.Is(err, docdb.ErrInvalidPatch), errors.Is(err, docdb.ErrInvalidID), errors.Is(err, docdb.ErrInvalidExpiry):
+			errResponse(w, http.StatusBadRequest, err)
+		case errors.Is(err, docdb.ErrTestFailed):
+			errResponse(w, http.StatusConflict, err)
+		case errors.Is(err, docdb.ErrConflict):
+			errResponse(w, http.StatusPreconditionFailed, nil)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", etag(rev))
+	response(w, http.StatusOK, map[string]any{
+		"id": id,
+	})
+}
+
+func (s Server) DeleteDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := db.Delete(id, conditions(r)...); err != nil {
+		switch {
+		case errors.Is(err, docdb.ErrNotFound):
+			errResponse(w, http.StatusNotFound, nil)
+		case errors.Is(err, docdb.ErrConflict):
+			errResponse(w, http.StatusPreconditionFailed, nil)
+		default:
+			errResponse(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s Server) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := db.Stats()
+	if err != nil {
+		errResponse(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	response(w, http.StatusOK, map[string]any{
+		"documents": stats.Documents,
+		"expiring":  stats.Expiring,
+		"expired":   stats.Expired,
+	})
+}
+
 func (s Server) Start() error {
 	go func() {
 		if err := s.server.ListenAndServe(); err != nil {
@@ -103,7 +331,13 @@ func (s Server) Start() error {
 func (s Server) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), s.wait)
 	defer cancel()
-	return s.server.Shutdown(ctx)
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := s.collections.Close(); err != nil {
+		return err
+	}
+	return s.docdb.Close()
 }
 
 func (s Server) waitSignal(sigs ...os.Signal) {
@@ -112,16 +346,21 @@ func (s Server) waitSignal(sigs ...os.Signal) {
 	<-c
 }
 
-func NewServer(addr string, port int) Server {
+func NewServer(addr string, port int, db *docdb.DocDB, collections *docdb.Collections, opts ...Option) Server {
 	s := Server{
-		docdb: docdb.NewDocDB(),
+		docdb:       db,
+		collections: collections,
 		server: &http.Server{
 			Addr:         fmt.Sprintf("%s:%d", addr, port),
 			WriteTimeout: 15 * time.Second,
 			ReadTimeout:  15 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		},
-		wait: 15 * time.Second,
+		wait:   15 * time.Second,
+		limits: query.DefaultLimits,
+	}
+	for _, opt := range opts {
+		opt(&s)
 	}
 
 	with := withMiddleware(withUID, withLogging)
@@ -130,6 +369,30 @@ func NewServer(addr string, port int) Server {
 	r.HandleFunc("/docs", with(s.AddDocumentHandler)).Methods("POST")
 	r.HandleFunc("/docs", with(s.SearchDocumentsHandler)).Methods("GET")
 	r.HandleFunc("/docs/{id}", with(s.GetDocumentHandler)).Methods("GET")
+	r.HandleFunc("/docs/{id}/history", with(s.DocumentHistoryHandler)).Methods("GET")
+	r.HandleFunc("/docs/{id}/diff", with(s.DocumentDiffHandler)).Methods("GET")
+	r.HandleFunc("/docs/{id}", with(s.UpdateDocumentHandler)).Methods("PUT")
+	r.HandleFunc("/docs/{id}", with(s.PatchDocumentHandler)).Methods("PATCH")
+	r.HandleFunc("/docs/{id}", with(s.DeleteDocumentHandler)).Methods("DELETE")
+	r.HandleFunc("/stats", with(s.StatsHandler)).Methods("GET")
+	r.HandleFunc("/_tx", with(s.TransactionHandler)).Methods("POST")
+	r.HandleFunc("/indexes", with(s.CreateIndexHandler)).Methods("POST")
+	r.HandleFunc("/indexes", with(s.ListIndexesHandler)).Methods("GET")
+	r.HandleFunc("/collections", with(s.CreateCollectionHandler)).Methods("POST")
+	r.HandleFunc("/collections", with(s.ListCollectionsHandler)).Methods("GET")
+	r.HandleFunc("/collections/{collection}", with(s.DropCollectionHandler)).Methods("DELETE")
+	r.HandleFunc("/collections/{collection}/stats", with(s.StatsHandler)).Methods("GET")
+	r.HandleFunc("/collections/{collection}/_tx", with(s.TransactionHandler)).Methods("POST")
+	r.HandleFunc("/collections/{collection}/indexes", with(s.CreateIndexHandler)).Methods("POST")
+	r.HandleFunc("/collections/{collection}/indexes", with(s.ListIndexesHandler)).Methods("GET")
+	r.HandleFunc("/collections/{collection}/docs", with(s.AddDocumentHandler)).Methods("POST")
+	r.HandleFunc("/collections/{collection}/docs", with(s.SearchDocumentsHandler)).Methods("GET")
+	r.HandleFunc("/collections/{collection}/docs/{id}", with(s.GetDocumentHandler)).Methods("GET")
+	r.HandleFunc("/collections/{collection}/docs/{id}/history", with(s.DocumentHistoryHandler)).Methods("GET")
+	r.HandleFunc("/collections/{collection}/docs/{id}/diff", with(s.DocumentDiffHandler)).Methods("GET")
+	r.HandleFunc("/collections/{collection}/docs/{id}", with(s.UpdateDocumentHandler)).Methods("PUT")
+	r.HandleFunc("/collections/{collection}/docs/{id}", with(s.PatchDocumentHandler)).Methods("PATCH")
+	r.HandleFunc("/collections/{collection}/docs/{id}", with(s.DeleteDocumentHandler)).Methods("DELETE")
 	r.HandleFunc("/", with(s.defaultHandler))
 	s.server.Handler = r
 
@@ -146,6 +409,29 @@ func response(w http.ResponseWriter, code int, body map[string]any) {
 	w.Write(b)
 }
 
+func setTTL(r *http.Request, doc map[string]any) error {
+	v := r.URL.Query().Get("ttl")
+	if v == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil || ttl <= 0 {
+		return fmt.Errorf("invalid ttl: %s", v)
+	}
+	doc["_expiresAt"] = time.Now().Add(ttl).Format(time.RFC3339Nano)
+	return nil
+}
+
+// uniqueResponse tells which field conflicts, and which document holds the
+// value.
+func uniqueResponse(w http.ResponseWriter, err *docdb.UniqueError) {
+	response(w, http.StatusConflict, map[string]any{
+		"error": err.Error(),
+		"field": err.Field,
+		"id":    err.ID,
+	})
+}
+
 func errResponse(w http.ResponseWriter, code int, err error) {
 	body := map[string]any{}
 	if err != nil {
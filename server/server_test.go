@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +15,7 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/gorilla/mux"
 	"github.com/x-color/docdb-in-go/docdb"
+	"github.com/x-color/docdb-in-go/query"
 )
 
 func TestServer_AddDocumentHandler(t *testing.T) {
@@ -22,6 +24,7 @@ func TestServer_AddDocumentHandler(t *testing.T) {
 		server   Server
 		reqBody  string
 		wantCode int
+		wantID   string
 		wantDoc  map[string]any
 	}{
 		{
@@ -43,6 +46,26 @@ func TestServer_AddDocumentHandler(t *testing.T) {
 			reqBody:  `{"greeting":"hello"`,
 			wantCode: http.StatusBadRequest,
 		},
+		{
+			name: "Create document with id field",
+			server: Server{
+				docdb: docdb.NewDocDB(docdb.WithIDField("sku")),
+			},
+			reqBody:  `{"sku":"B-12"}`,
+			wantCode: http.StatusCreated,
+			wantID:   "B-12",
+			wantDoc: map[string]any{
+				"sku": "B-12",
+			},
+		},
+		{
+			name: "Create document with invalid id field",
+			server: Server{
+				docdb: docdb.NewDocDB(docdb.WithIDField("sku")),
+			},
+			reqBody:  `{"sku":true}`,
+			wantCode: http.StatusBadRequest,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -70,10 +93,16 @@ func TestServer_AddDocumentHandler(t *testing.T) {
 			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
 				t.Errorf("handler returned invalid body: got %v", rr.Body.String())
 			}
-			v, err := tt.server.docdb.Get(res.ID)
+			if tt.wantID != "" && res.ID != tt.wantID {
+				t.Errorf("handler returned wrong id: got %v want %v", res.ID, tt.wantID)
+			}
+			v, rev, err := tt.server.docdb.GetWithRevision(res.ID)
 			if err != nil {
 				t.Errorf("can not get document: %v", err)
 			}
+			if got := rr.Header().Get("ETag"); got != etag(rev) {
+				t.Errorf("handler returned wrong ETag: got %v want %v", got, etag(rev))
+			}
 
 			if diff := cmp.Diff(tt.wantDoc, v); diff != "" {
 				t.Errorf("document mismatch (-want +got):\n%s", diff)
@@ -152,6 +181,308 @@ func TestServer_GetDocumentHandler(t *testing.T) {
 	}
 }
 
+func TestServer_UpdateDocumentHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     Server
+		overrideID string
+		reqBody    string
+		wantCode   int
+		wantDoc    map[string]any
+	}{
+		{
+			name: "Update document",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			reqBody:  `{"greeting":"hi"}`,
+			wantCode: http.StatusOK,
+			wantDoc: map[string]any{
+				"greeting": "hi",
+			},
+		},
+		{
+			name: "Update invalid document",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			reqBody:  `{"greeting":"hi"`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Create document with client-supplied id",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			overrideID: "new-id",
+			reqBody:    `{"greeting":"hi"}`,
+			wantCode:   http.StatusCreated,
+			wantDoc: map[string]any{
+				"greeting": "hi",
+			},
+		},
+		{
+			name: "Create document whose id field matches id",
+			server: Server{
+				docdb: docdb.NewDocDB(docdb.WithIDField("sku")),
+			},
+			overrideID: "B-13",
+			reqBody:    `{"sku":"B-13"}`,
+			wantCode:   http.StatusCreated,
+			wantDoc: map[string]any{
+				"sku": "B-13",
+			},
+		},
+		{
+			name: "Create document whose id field differs from id",
+			server: Server{
+				docdb: docdb.NewDocDB(docdb.WithIDField("sku")),
+			},
+			overrideID: "B-13",
+			reqBody:    `{"sku":"OTHER"}`,
+			wantCode:   http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testdata := map[string]any{
+				"greeting": "hello",
+			}
+			id, err := tt.server.docdb.Add(testdata)
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+
+			if tt.overrideID != "" {
+				id = tt.overrideID
+			}
+			req, err := http.NewRequest("PUT", "/docs/"+id, bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/docs/{id}", tt.server.UpdateDocumentHandler)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+
+			if rr.Code != http.StatusOK && rr.Code != http.StatusCreated {
+				return
+			}
+
+			v, err := tt.server.docdb.Get(id)
+			if err != nil {
+				t.Errorf("can not get document: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantDoc, v); diff != "" {
+				t.Errorf("document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServer_PatchDocumentHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		server      Server
+		overrideID  string
+		contentType string
+		reqBody     string
+		wantCode    int
+		wantDoc     map[string]any
+	}{
+		{
+			name: "Merge patch document",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			contentType: "application/merge-patch+json",
+			reqBody:     `{"detail":{"price":200},"greeting":null}`,
+			wantCode:    http.StatusOK,
+			wantDoc: map[string]any{
+				"detail": map[string]any{
+					"price":       float64(200),
+					"description": "sample",
+				},
+			},
+		},
+		{
+			name: "JSON patch document",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			contentType: "application/json-patch+json",
+			reqBody:     `[{"op":"test","path":"/detail/price","value":100},{"op":"replace","path":"/detail/price","value":200}]`,
+			wantCode:    http.StatusOK,
+			wantDoc: map[string]any{
+				"greeting": "hello",
+				"detail": map[string]any{
+					"price":       float64(200),
+					"description": "sample",
+				},
+			},
+		},
+		{
+			name: "JSON patch test failed",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			contentType: "application/json-patch+json",
+			reqBody:     `[{"op":"test","path":"/detail/price","value":150},{"op":"replace","path":"/detail/price","value":200}]`,
+			wantCode:    http.StatusConflict,
+		},
+		{
+			name: "Unsupported content type",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			contentType: "text/plain",
+			reqBody:     `{"greeting":"hi"}`,
+			wantCode:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "Invalid patch",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			contentType: "application/merge-patch+json",
+			reqBody:     `{"greeting":"hi"`,
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name: "Not found document",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			overrideID:  "not-found",
+			contentType: "application/merge-patch+json",
+			reqBody:     `{"greeting":"hi"}`,
+			wantCode:    http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testdata := map[string]any{
+				"greeting": "hello",
+				"detail": map[string]any{
+					"price":       100,
+					"description": "sample",
+				},
+			}
+			id, err := tt.server.docdb.Add(testdata)
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+
+			if tt.overrideID != "" {
+				id = tt.overrideID
+			}
+			req, err := http.NewRequest("PATCH", "/docs/"+id, bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/docs/{id}", tt.server.PatchDocumentHandler)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			v, err := tt.server.docdb.Get(id)
+			if err != nil {
+				t.Errorf("can not get document: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantDoc, v); diff != "" {
+				t.Errorf("document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServer_DeleteDocumentHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     Server
+		overrideID string
+		wantCode   int
+	}{
+		{
+			name: "Delete document",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name: "Not found document",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			overrideID: "not-found",
+			wantCode:   http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testdata := map[string]any{
+				"greeting": "hello",
+			}
+			id, err := tt.server.docdb.Add(testdata)
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+
+			if tt.overrideID != "" {
+				id = tt.overrideID
+			}
+			req, err := http.NewRequest("DELETE", "/docs/"+id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/docs/{id}", tt.server.DeleteDocumentHandler)
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+
+			if rr.Code != http.StatusNoContent {
+				return
+			}
+
+			if _, err := tt.server.docdb.Get(id); !errors.Is(err, docdb.ErrNotFound) {
+				t.Errorf("document is not deleted: %v", err)
+			}
+			q, err := query.ParseQuery("greeting:hello")
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs, err := tt.server.docdb.Search(q)
+			if err != nil {
+				t.Errorf("can not search documents after delete: %v", err)
+			}
+			if len(docs) != 0 {
+				t.Errorf("deleted document is found: %v", docs)
+			}
+		})
+	}
+}
+
 func TestServer_SearchDocumentHandler(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -177,7 +508,7 @@ func TestServer_SearchDocumentHandler(t *testing.T) {
 					"greeting": "hello",
 				},
 			},
-			q:        "obj.num:1",
+			q:        `obj.num:"1"`,
 			wantCode: http.StatusOK,
 			wantRes: map[string]any{
 				"documents": []any{
@@ -194,22 +525,64 @@ func TestServer_SearchDocumentHandler(t *testing.T) {
 			},
 		},
 		{
-			name: "Search documents",
+			name: "Search document by value of different type",
 			server: Server{
 				docdb: docdb.NewDocDB(),
 			},
 			docs: []map[string]any{
 				{
-					"greeting": "hello",
-					"num":      1,
-				},
-				{
-					"greeting": "hello",
-					"num":      2,
+					"obj": map[string]any{
+						"num": "1",
+					},
 				},
+			},
+			q:        "obj.num:1",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "Search document with coercion",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
 				{
-					"greeting": "hi",
-					"num":      3,
+					"obj": map[string]any{
+						"num": "1",
+					},
+				},
+			},
+			q:        "obj.num:1&coerce=true",
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"documents": []any{
+					map[string]any{
+						"document": map[string]any{
+							"obj": map[string]any{
+								"num": "1",
+							},
+						},
+					},
+				},
+				"count": float64(1),
+			},
+		},
+		{
+			name: "Search documents",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
+				{
+					"greeting": "hello",
+					"num":      1,
+				},
+				{
+					"greeting": "hello",
+					"num":      2,
+				},
+				{
+					"greeting": "hi",
+					"num":      3,
 				},
 			},
 			q:        "num:>1",
@@ -232,6 +605,193 @@ func TestServer_SearchDocumentHandler(t *testing.T) {
 				"count": float64(2),
 			},
 		},
+		{
+			name: "Search documents between bounds",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
+				{
+					"num": 1,
+				},
+				{
+					"num": 2,
+				},
+				{
+					"num": 3,
+				},
+			},
+			q:        "num:[1%20TO%203}",
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"documents": []any{
+					map[string]any{
+						"document": map[string]any{
+							"num": float64(1),
+						},
+					},
+					map[string]any{
+						"document": map[string]any{
+							"num": float64(2),
+						},
+					},
+				},
+				"count": float64(2),
+			},
+		},
+		{
+			name: "Search documents by prefix",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
+				{
+					"name": "bookA",
+				},
+				{
+					"name": "notebook",
+				},
+			},
+			q:        "name:book*",
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"documents": []any{
+					map[string]any{
+						"document": map[string]any{
+							"name": "bookA",
+						},
+					},
+				},
+				"count": float64(1),
+			},
+		},
+		{
+			name: "Search documents missing a field",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
+				{
+					"name":   "bookA",
+					"detail": map[string]any{"price": 100},
+				},
+				{
+					"name": "bookB",
+				},
+			},
+			q:        "missing:detail.price",
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"documents": []any{
+					map[string]any{
+						"document": map[string]any{
+							"name": "bookB",
+						},
+					},
+				},
+				"count": float64(1),
+			},
+		},
+		{
+			name: "Search documents by either query",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
+				{
+					"greeting": "hello",
+					"num":      1,
+				},
+				{
+					"greeting": "hello",
+					"num":      2,
+				},
+				{
+					"greeting": "hi",
+					"num":      3,
+				},
+			},
+			q:        "num:1%20OR%20greeting:hi",
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"documents": []any{
+					map[string]any{
+						"document": map[string]any{
+							"greeting": "hello",
+							"num":      float64(1),
+						},
+					},
+					map[string]any{
+						"document": map[string]any{
+							"greeting": "hi",
+							"num":      float64(3),
+						},
+					},
+				},
+				"count": float64(2),
+			},
+		},
+		{
+			name: "Search documents by array element",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
+				{
+					"tags": []any{"go", "db"},
+				},
+				{
+					"tags": []any{"rust"},
+				},
+			},
+			q:        "tags:go",
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"documents": []any{
+					map[string]any{
+						"document": map[string]any{
+							"tags": []any{"go", "db"},
+						},
+					},
+				},
+				"count": float64(1),
+			},
+		},
+		{
+			name: "Search documents by array position",
+			server: Server{
+				docdb: docdb.NewDocDB(),
+			},
+			docs: []map[string]any{
+				{
+					"authors": []any{map[string]any{"name": "alice"}, map[string]any{"name": "bob"}},
+				},
+				{
+					"authors": []any{map[string]any{"name": "bob"}},
+				},
+			},
+			q:        "authors.0.name:bob",
+			wantCode: http.StatusOK,
+			wantRes: map[string]any{
+				"documents": []any{
+					map[string]any{
+						"document": map[string]any{
+							"authors": []any{map[string]any{"name": "bob"}},
+						},
+					},
+				},
+				"count": float64(1),
+			},
+		},
+		{
+			name: "Too complex query",
+			server: Server{
+				docdb:  docdb.NewDocDB(),
+				limits: query.Limits{MaxTerms: 2},
+			},
+			q:        "num:1%20OR%20num:2%20OR%20num:3",
+			wantCode: http.StatusBadRequest,
+		},
 		{
 			name: "Not found document",
 			server: Server{
@@ -311,3 +871,183 @@ func TestServer_SearchDocumentHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_ConditionalRequests(t *testing.T) {
+	type request struct {
+		method      string
+		body        string
+		ifMatch     string
+		ifNoneMatch string
+		wantCode    int
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "Update with current ETag",
+			requests: []request{
+				{method: "PUT", body: `{"price":200}`, ifMatch: "current", wantCode: http.StatusOK},
+				{method: "PATCH", body: `{"price":300}`, ifMatch: "current", wantCode: http.StatusOK},
+				{method: "DELETE", ifMatch: "current", wantCode: http.StatusNoContent},
+			},
+		},
+		{
+			name: "Update with stale ETag",
+			requests: []request{
+				{method: "PUT", body: `{"price":200}`, ifMatch: `"0"`, wantCode: http.StatusPreconditionFailed},
+				{method: "PATCH", body: `{"price":300}`, ifMatch: `W/"1"`, wantCode: http.StatusPreconditionFailed},
+				{method: "DELETE", ifMatch: `"0", "12345"`, wantCode: http.StatusPreconditionFailed},
+			},
+		},
+		{
+			name: "Conditional get and create",
+			requests: []request{
+				{method: "GET", ifNoneMatch: "current", wantCode: http.StatusNotModified},
+				{method: "GET", ifNoneMatch: `"0"`, wantCode: http.StatusOK},
+				{method: "PUT", body: `{"price":200}`, ifNoneMatch: "*", wantCode: http.StatusPreconditionFailed},
+				{method: "PUT", body: `{"price":200}`, ifMatch: "*", wantCode: http.StatusOK},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := docdb.NewCollections("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer("localhost", 0, docdb.NewDocDB(), cs)
+			id, err := s.docdb.Add(map[string]any{"price": 100})
+			if err != nil {
+				t.Fatalf("failed to add data to DB for preparing test: %v", err)
+			}
+
+			for _, rq := range tt.requests {
+				req, err := http.NewRequest("GET", "/docs/"+id, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				s.server.Handler.ServeHTTP(rr, req)
+				current := rr.Header().Get("ETag")
+
+				req, err = http.NewRequest(rq.method, "/docs/"+id, bytes.NewBufferString(rq.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+				for h, v := range map[string]string{"If-Match": rq.ifMatch, "If-None-Match": rq.ifNoneMatch} {
+					if v == "current" {
+						v = current
+					}
+					if v != "" {
+						req.Header.Set(h, v)
+					}
+				}
+
+				rr = httptest.NewRecorder()
+				s.server.Handler.ServeHTTP(rr, req)
+
+				if rr.Code != rq.wantCode {
+					t.Errorf("%s returned wrong status code: got %v want %v", rq.method, rr.Code, rq.wantCode)
+				}
+				if (rr.Code == http.StatusOK || rr.Code == http.StatusCreated) && rr.Header().Get("ETag") == "" {
+					t.Errorf("%s returned no ETag", rq.method)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_DocumentTTL(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		reqBody    string
+		wantCode   int
+		wantExpiry bool
+	}{
+		{
+			name:       "Create document with ttl",
+			path:       "/docs?ttl=1h",
+			reqBody:    `{"greeting":"hello"}`,
+			wantCode:   http.StatusCreated,
+			wantExpiry: true,
+		},
+		{
+			name:       "Create document with expiry field",
+			path:       "/docs",
+			reqBody:    `{"greeting":"hello","_expiresAt":"2100-01-01T00:00:00Z"}`,
+			wantCode:   http.StatusCreated,
+			wantExpiry: true,
+		},
+		{
+			name:       "Create document without ttl",
+			path:       "/docs",
+			reqBody:    `{"greeting":"hello"}`,
+			wantCode:   http.StatusCreated,
+			wantExpiry: false,
+		},
+		{
+			name:     "Create document with invalid ttl",
+			path:     "/docs?ttl=-1h",
+			reqBody:  `{"greeting":"hello"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Create document with invalid expiry field",
+			path:     "/docs",
+			reqBody:  `{"greeting":"hello","_expiresAt":1}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := docdb.NewCollections("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer("localhost", 0, docdb.NewDocDB(), cs)
+
+			req, err := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+			if rr.Code != http.StatusCreated {
+				return
+			}
+
+			res := struct {
+				ID string
+			}{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Errorf("handler returned invalid body: got %v", rr.Body.String())
+			}
+			doc, err := s.docdb.Get(res.ID)
+			if err != nil {
+				t.Fatalf("can not get document: %v", err)
+			}
+			if _, ok := doc["_expiresAt"]; ok != tt.wantExpiry {
+				t.Errorf("document expiry = %v, wantExpiry %v", doc["_expiresAt"], tt.wantExpiry)
+			}
+
+			stats, err := s.docdb.Stats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantExpiring := 0
+			if tt.wantExpiry {
+				wantExpiring = 1
+			}
+			if stats.Expiring != wantExpiring {
+				t.Errorf("expiring documents = %v, want %v", stats.Expiring, wantExpiring)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+var errInvalidOperation = errors.New("invalid operation")
+
+type txOperation struct {
+	Op      string         `json:"op"`
+	ID      string         `json:"id"`
+	Doc     map[string]any `json:"doc"`
+	IfMatch *uint64        `json:"ifMatch"`
+}
+
+// TransactionHandler applies a list of operations all-or-nothing. The results
+// are in the order of the operations.
+func (s Server) TransactionHandler(w http.ResponseWriter, r *http.Request) {
+	db, ok := s.documents(w, r)
+	if !ok {
+		return
+	}
+
+	body := struct {
+		Ops []txOperation `json:"ops"`
+	}{}
+	dc := json.NewDecoder(r.Body)
+	if err := dc.Decode(&body); err != nil {
+		errResponse(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(body.Ops) == 0 {
+		errResponse(w, http.StatusBadRequest, fmt.Errorf("%w: no operations", errInvalidOperation))
+		return
+	}
+
+	tx := db.Begin()
+	results := make([]map[string]any, 0, len(body.Ops))
+	for i, op := range body.Ops {
+		res, err := applyTxOperation(tx, op)
+		if err != nil {
+			tx.Rollback()
+			txErrResponse(w, i, err)
+			return
+		}
+		results = append(results, res)
+	}
+
+	revs, err := tx.Commit()
+	if err != nil {
+		txErrResponse(w, -1, err)
+		return
+	}
+	for i, res := range results {
+		if body.Ops[i].Op == "get" {
+			continue
+		}
+		if rev, ok := revs[res["id"].(string)]; ok {
+			res["rev"] = rev
+		}
+	}
+
+	response(w, http.StatusOK, map[string]any{
+		"results": results,
+	})
+}
+
+func applyTxOperation(tx *docdb.Tx, op txOperation) (map[string]any, error) {
+	if op.Op != "add" && op.ID == "" {
+		return nil, fmt.Errorf("%w: %s needs an id", errInvalidOperation, op.Op)
+	}
+	if (op.Op == "add" || op.Op == "put" || op.Op == "update") && op.Doc == nil {
+		return nil, fmt.Errorf("%w: %s needs a doc", errInvalidOperation, op.Op)
+	}
+	var conds []docdb.Condition
+	if op.IfMatch != nil {
+		conds = append(conds, docdb.IfMatch(*op.IfMatch))
+	}
+
+	var err error
+	switch op.Op {
+	case "get":
+		doc, rev, err := tx.Get(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if op.IfMatch != nil && rev != *op.IfMatch {
+			return nil, docdb.ErrConflict
+		}
+		return map[string]any{"id": op.ID, "rev": rev, "document": doc}, nil
+	case "add":
+		op.ID, err = tx.Add(op.Doc)
+	case "put":
+		err = tx.Upsert(op.ID, op.Doc, conds...)
+	case "update":
+		err = tx.Update(op.ID, op.Doc, conds...)
+	case "delete":
+		err = tx.Delete(op.ID, conds...)
+	default:
+		err = fmt.Errorf("%w: %q", errInvalidOperation, op.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"id": op.ID}, nil
+}
+
+// txErrResponse tells why a transaction failed, and which operation failed
+// when i is not negative.
+func txErrResponse(w http.ResponseWriter, i int, err error) {
+	var ue *docdb.UniqueError
+	if errors.As(err, &ue) {
+		uniqueResponse(w, ue)
+		return
+	}
+
+	var code int
+	switch {
+	case errors.Is(err, errInvalidOperation), errors.Is(err, docdb.ErrInvalidID), errors.Is(err, docdb.ErrInvalidExpiry):
+		code = http.StatusBadRequest
+	case errors.Is(err, docdb.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, docdb.ErrAlreadyExists), errors.Is(err, docdb.ErrTxConflict):
+		code = http.StatusConflict
+	case errors.Is(err, docdb.ErrConflict):
+		code = http.StatusPreconditionFailed
+	default:
+		errResponse(w, http.StatusInternalServerError, nil)
+		return
+	}
+
+	body := map[string]any{"error": err.Error()}
+	if i >= 0 {
+		body["op"] = i
+	}
+	response(w, code, body)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/x-color/docdb-in-go/docdb"
+)
+
+func TestServer_TransactionHandler(t *testing.T) {
+	type request struct {
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantRes  map[string]any
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "Commit operations",
+			requests: []request{
+				{method: "PUT", path: "/docs/stock", body: `{"qty":10}`, wantCode: http.StatusCreated},
+				{
+					method:   "POST",
+					path:     "/_tx",
+					body:     `{"ops":[{"op":"get","id":"stock"},{"op":"update","id":"stock","doc":{"qty":8},"ifMatch":1},{"op":"put","id":"order","doc":{"qty":2}}]}`,
+					wantCode: http.StatusOK,
+					wantRes: map[string]any{
+						"results": []any{
+							map[string]any{"id": "stock", "rev": float64(1), "document": map[string]any{"qty": float64(10)}},
+							map[string]any{"id": "stock", "rev": float64(2)},
+							map[string]any{"id": "order", "rev": float64(3)},
+						},
+					},
+				},
+				{method: "GET", path: "/docs/order", wantCode: http.StatusOK, wantRes: map[string]any{"qty": float64(2)}},
+			},
+		},
+		{
+			name: "Operations are applied all-or-nothing",
+			requests: []request{
+				{method: "PUT", path: "/docs/stock", body: `{"qty":10}`, wantCode: http.StatusCreated},
+				{
+					method:   "POST",
+					path:     "/_tx",
+					body:     `{"ops":[{"op":"put","id":"order","doc":{"qty":2}},{"op":"update","id":"stock","doc":{"qty":8},"ifMatch":5}]}`,
+					wantCode: http.StatusPreconditionFailed,
+					wantRes:  map[string]any{"error": "conflict error", "op": float64(1)},
+				},
+				{method: "GET", path: "/docs/order", wantCode: http.StatusNotFound},
+				{
+					method:   "POST",
+					path:     "/_tx",
+					body:     `{"ops":[{"op":"put","id":"order","doc":{"qty":2}},{"op":"delete","id":"missing"}]}`,
+					wantCode: http.StatusNotFound,
+					wantRes:  map[string]any{"error": "not found error", "op": float64(1)},
+				},
+				{method: "GET", path: "/docs/order", wantCode: http.StatusNotFound},
+			},
+		},
+		{
+			name: "Invalid operations",
+			requests: []request{
+				{method: "POST", path: "/_tx", body: `{"ops":[]}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/_tx", body: `{"ops":[{"op":"move","id":"a"}]}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/_tx", body: `{"ops":[{"op":"update","id":"a"}]}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/_tx", body: `{`, wantCode: http.StatusBadRequest},
+			},
+		},
+		{
+			name: "Transaction in collection",
+			requests: []request{
+				{method: "POST", path: "/collections", body: `{"name":"books"}`, wantCode: http.StatusCreated},
+				{method: "POST", path: "/collections/books/_tx", body: `{"ops":[{"op":"put","id":"a","doc":{"name":"bookA"}}]}`, wantCode: http.StatusOK},
+				{method: "GET", path: "/collections/books/docs/a", wantCode: http.StatusOK},
+				{method: "GET", path: "/docs/a", wantCode: http.StatusNotFound},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := docdb.NewCollections("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewServer("localhost", 0, docdb.NewDocDB(), cs)
+
+			for _, rq := range tt.requests {
+				req, err := http.NewRequest(rq.method, rq.path, bytes.NewBufferString(rq.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				rr := httptest.NewRecorder()
+				s.server.Handler.ServeHTTP(rr, req)
+
+				if rr.Code != rq.wantCode {
+					t.Errorf("%s %s returned wrong status code: got %v want %v", rq.method, rq.path, rr.Code, rq.wantCode)
+				}
+
+				if rq.wantRes == nil {
+					continue
+				}
+				res := make(map[string]any)
+				if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+					t.Errorf("handler returned invalid body: got %v", rr.Body.String())
+				}
+				if diff := cmp.Diff(rq.wantRes, res); diff != "" {
+					t.Errorf("%s %s mismatch (-want +got):\n%s", rq.method, rq.path, diff)
+				}
+			}
+		})
+	}
+}